@@ -0,0 +1,580 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"log/slog"
+	"maps"
+	"net/http"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+var initialisms = map[string]bool{
+	"API": true, "HTTP": true, "HTTPS": true, "ID": true, "IP": true, "JSON": true,
+	"SQL": true, "TLS": true, "UID": true, "URI": true, "URL": true, "UUID": true,
+}
+
+var wordPattern = regexp.MustCompile(`[A-Z]+[a-z0-9]*|[a-z0-9]+`)
+
+func goName(s string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		for _, word := range wordPattern.FindAllString(part, -1) {
+			if upper := strings.ToUpper(word); initialisms[upper] {
+				b.WriteString(upper)
+				continue
+			}
+			b.WriteString(strings.ToUpper(word[:1]) + word[1:])
+		}
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+type generator struct {
+	doc     *document
+	pkg     string
+	imports map[string]bool
+	types   bytes.Buffer
+	methods bytes.Buffer
+	defined map[string]bool
+}
+
+func generate(doc *document, pkg string) ([]byte, error) {
+	g := &generator{
+		doc: doc,
+		pkg: pkg,
+		imports: map[string]bool{
+			"context":                            true,
+			"github.com/nphiro/mesh/pkg/client":  true,
+			"go.opentelemetry.io/otel":           true,
+			"go.opentelemetry.io/otel/codes":     true,
+			"go.opentelemetry.io/otel/trace":     true,
+			"go.opentelemetry.io/otel/attribute": true,
+			"fmt":                                true,
+		},
+		defined: map[string]bool{},
+	}
+	for _, name := range slices.Sorted(maps.Keys(doc.Components.Schemas)) {
+		g.namedType(goName(name), doc.Components.Schemas[name])
+	}
+	for _, path := range slices.Sorted(maps.Keys(doc.Paths)) {
+		item := doc.Paths[path]
+		for _, o := range item.operations() {
+			if err := g.operation(path, o.method, item.Parameters, o.op); err != nil {
+				return nil, fmt.Errorf("%s %s: %w", strings.ToUpper(o.method), path, err)
+			}
+		}
+	}
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by clientgen. DO NOT EDIT.\n\npackage %s\n\nimport (\n", pkg)
+	var std, external []string
+	for _, imp := range slices.Sorted(maps.Keys(g.imports)) {
+		if strings.Contains(strings.Split(imp, "/")[0], ".") {
+			external = append(external, imp)
+		} else {
+			std = append(std, imp)
+		}
+	}
+	for _, imp := range std {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString("\n")
+	for _, imp := range external {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	fmt.Fprintf(&out, ")\n\nconst tracerName = %q\n\n", "mesh.clientgen/"+pkg)
+	out.WriteString(clientPreamble)
+	out.Write(g.types.Bytes())
+	out.Write(g.methods.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("format generated source: %w", err)
+	}
+	return formatted, nil
+}
+
+const clientPreamble = `type Client struct {
+	client client.Client
+}
+
+func New(c client.Client) *Client {
+	return &Client{client: c}
+}
+
+// UnexpectedStatusError is returned for responses the API document does not describe.
+type UnexpectedStatusError struct {
+	Operation  string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.Operation, e.StatusCode)
+}
+
+func fail(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+`
+
+func comment(buf *bytes.Buffer, text string) {
+	for _, line := range strings.Split(strings.TrimSpace(text), "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			fmt.Fprintf(buf, "// %s\n", line)
+		}
+	}
+}
+
+func (g *generator) namedType(name string, s *schema) {
+	if g.defined[name] {
+		return
+	}
+	g.defined[name] = true
+	var buf bytes.Buffer
+	comment(&buf, s.Description)
+	if isStruct(s) {
+		fmt.Fprintf(&buf, "type %s struct {\n", name)
+		g.fields(&buf, name, s)
+		buf.WriteString("}\n\n")
+	} else {
+		fmt.Fprintf(&buf, "type %s %s\n\n", name, g.goType(s, name))
+		if s.typeName() == "string" && len(s.Enum) > 0 {
+			buf.WriteString("const (\n")
+			for _, v := range s.Enum {
+				if v, ok := v.(string); ok {
+					fmt.Fprintf(&buf, "\t%s%s %s = %q\n", name, goName(v), name, v)
+				}
+			}
+			buf.WriteString(")\n\n")
+		}
+	}
+	g.types.Write(buf.Bytes())
+}
+
+func isStruct(s *schema) bool {
+	if s.Ref != "" {
+		return false
+	}
+	if len(s.AllOf) > 1 {
+		return true
+	}
+	return (s.typeName() == "object" || s.typeName() == "") && len(s.Properties) > 0
+}
+
+// fields writes struct fields for s, flattening allOf members into one struct.
+func (g *generator) fields(buf *bytes.Buffer, parent string, s *schema) {
+	for _, member := range s.AllOf {
+		if member.Ref != "" {
+			member = g.doc.Components.Schemas[refName(member.Ref)]
+		}
+		if member != nil {
+			g.fields(buf, parent, member)
+		}
+	}
+	for _, prop := range slices.Sorted(maps.Keys(s.Properties)) {
+		ps := s.Properties[prop]
+		field := goName(prop)
+		typ := g.goType(ps, parent+field)
+		tag := prop
+		if !slices.Contains(s.Required, prop) {
+			tag += ",omitempty"
+			typ = optional(typ)
+		}
+		comment(buf, ps.Description)
+		fmt.Fprintf(buf, "\t%s %s `json:%q`\n", field, typ, tag)
+	}
+}
+
+func optional(typ string) string {
+	if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || strings.HasPrefix(typ, "*") ||
+		typ == "any" || typ == "json.RawMessage" {
+		return typ
+	}
+	return "*" + typ
+}
+
+func (g *generator) goType(s *schema, hint string) string {
+	if s == nil {
+		return "any"
+	}
+	if s.Ref != "" {
+		return goName(refName(s.Ref))
+	}
+	if len(s.AllOf) == 1 {
+		return g.goType(s.AllOf[0], hint)
+	}
+	if isStruct(s) {
+		g.namedType(hint, s)
+		return hint
+	}
+	if len(s.OneOf) > 0 || len(s.AnyOf) > 0 {
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	}
+	switch s.typeName() {
+	case "string":
+		switch s.Format {
+		case "date-time":
+			g.imports["time"] = true
+			return "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		if s.Format == "int32" {
+			return "int32"
+		}
+		return "int64"
+	case "number":
+		if s.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(s.Items, hint+"Item")
+	case "object":
+		if additional, ok := s.AdditionalProperties.(map[string]any); ok && len(additional) > 0 {
+			var items schema
+			if ref, ok := additional["$ref"].(string); ok {
+				items.Ref = ref
+			} else {
+				items.Type, _ = additional["type"].(string)
+				items.Format, _ = additional["format"].(string)
+			}
+			return "map[string]" + g.goType(&items, hint+"Value")
+		}
+		return "map[string]any"
+	}
+	return "any"
+}
+
+type param struct {
+	*parameter
+	field string
+	typ   string
+}
+
+func (g *generator) operation(path, method string, shared []*parameter, op *operation) error {
+	if method != "Get" && method != "Post" && method != "Put" && method != "Patch" && method != "Delete" {
+		slog.Warn("Skipping operation with unsupported method", slog.String("method", method), slog.String("path", path))
+		return nil
+	}
+	opID := op.OperationID
+	if opID == "" {
+		opID = method + " " + path
+	}
+	name := goName(opID)
+
+	var params []param
+	for _, raw := range append(slices.Clone(shared), op.Parameters...) {
+		p, err := g.doc.parameter(raw)
+		if err != nil {
+			return err
+		}
+		if p.In == "cookie" {
+			continue
+		}
+		field := goName(p.Name)
+		typ := g.goType(p.Schema, name+field)
+		if p.In != "path" && !p.Required {
+			typ = optional(typ)
+		}
+		params = slices.DeleteFunc(params, func(existing param) bool {
+			return existing.Name == p.Name && existing.In == p.In
+		})
+		params = append(params, param{p, field, typ})
+	}
+
+	var bodyType string
+	if op.RequestBody != nil {
+		body, err := g.doc.requestBody(op.RequestBody)
+		if err != nil {
+			return err
+		}
+		if s := jsonSchema(body.Content); s != nil {
+			bodyType = g.goType(s, name+"Request")
+		}
+	}
+	if bodyType != "" && method == "Delete" {
+		slog.Warn("Ignoring request body of DELETE operation", slog.String("operation", opID))
+		bodyType = ""
+	}
+
+	var (
+		resultType string
+		statuses   = slices.Sorted(maps.Keys(op.Responses))
+		responses  = map[string]*response{}
+	)
+	for _, status := range statuses {
+		res, err := g.doc.response(op.Responses[status])
+		if err != nil {
+			return err
+		}
+		responses[status] = res
+		if strings.HasPrefix(status, "2") && resultType == "" {
+			if s := jsonSchema(res.Content); s != nil {
+				resultType = g.goType(s, name+"Response")
+			}
+		}
+	}
+
+	if len(params) > 0 {
+		fmt.Fprintf(&g.types, "type %sParams struct {\n", name)
+		for _, p := range params {
+			comment(&g.types, p.Description)
+			fmt.Fprintf(&g.types, "\t%s %s\n", p.field, p.typ)
+		}
+		g.types.WriteString("}\n\n")
+	}
+
+	m := &g.methods
+	comment(m, op.Summary)
+	if op.Summary == "" {
+		comment(m, op.Description)
+	}
+	if op.Deprecated {
+		m.WriteString("//\n// Deprecated: the API document marks this operation as deprecated.\n")
+	}
+	fmt.Fprintf(m, "func (c *Client) %s(ctx context.Context", name)
+	if len(params) > 0 {
+		fmt.Fprintf(m, ", params %sParams", name)
+	}
+	if bodyType != "" {
+		fmt.Fprintf(m, ", body %s", bodyType)
+	}
+	ret, zero := "error", ""
+	if resultType != "" {
+		ret, zero = "("+resultRef(resultType)+", error)", "nil, "
+	}
+	fmt.Fprintf(m, ") %s {\n", ret)
+	fmt.Fprintf(m, "ctx, span := otel.Tracer(tracerName).Start(ctx, %q, trace.WithSpanKind(trace.SpanKindClient))\n", opID)
+	m.WriteString("defer span.End()\n\n")
+
+	fmt.Fprintf(m, "path := %s\n", g.pathExpr(path, params))
+	if g.queryAndHeaders(params) {
+		m.WriteString("\n")
+	}
+
+	switch {
+	case method == "Get" || method == "Delete":
+		fmt.Fprintf(m, "res, err := c.client.%s(ctx, path)\n", method)
+	case bodyType != "":
+		fmt.Fprintf(m, "res, err := c.client.%s(ctx, path, body)\n", method)
+	default:
+		fmt.Fprintf(m, "res, err := c.client.%s(ctx, path, nil)\n", method)
+	}
+	fmt.Fprintf(m, "if err != nil {\nreturn %sfail(span, err)\n}\n", zero)
+	m.WriteString("span.SetAttributes(attribute.Int(\"http.response.status_code\", res.StatusCode))\n\n")
+
+	var ranges []string
+	m.WriteString("switch res.StatusCode {\n")
+	for _, status := range statuses {
+		code, err := strconv.Atoi(status)
+		if err != nil {
+			ranges = append(ranges, status)
+			continue
+		}
+		fmt.Fprintf(m, "case %d:\n", code)
+		g.responseCase(m, name, opID, status, responses[status], resultType, zero)
+	}
+	m.WriteString("}\n")
+	for _, status := range ranges {
+		switch upper := strings.ToUpper(status); {
+		case upper == "DEFAULT":
+			continue
+		case len(upper) == 3 && strings.HasSuffix(upper, "XX"):
+			fmt.Fprintf(m, "if res.StatusCode/100 == %c {\n", upper[0])
+			g.responseCase(m, name, opID, upper, responses[status], resultType, zero)
+			m.WriteString("}\n")
+		}
+	}
+	if res, ok := responses["default"]; ok {
+		g.responseCase(m, name, opID, "default", res, resultType, zero)
+	} else {
+		fmt.Fprintf(m, "return %sfail(span, &UnexpectedStatusError{Operation: %q, StatusCode: res.StatusCode, Body: res.Body})\n", zero, opID)
+	}
+	m.WriteString("}\n\n")
+	return nil
+}
+
+// resultRef returns how an operation hands back its result:
+// slices, maps and dynamic values as-is, everything else by pointer.
+func resultRef(typ string) string {
+	if strings.HasPrefix(typ, "[]") || strings.HasPrefix(typ, "map[") || typ == "any" || typ == "json.RawMessage" {
+		return typ
+	}
+	return "*" + typ
+}
+
+func (g *generator) pathExpr(path string, params []param) string {
+	var parts []string
+	for path != "" {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			parts = append(parts, strconv.Quote(path))
+			break
+		}
+		if start > 0 {
+			parts = append(parts, strconv.Quote(path[:start]))
+		}
+		field := goName(path[start+1 : end])
+		typ := "string"
+		for _, p := range params {
+			if p.In == "path" && p.Name == path[start+1:end] {
+				field, typ = p.field, p.typ
+			}
+		}
+		g.imports["net/url"] = true
+		parts = append(parts, "url.PathEscape("+g.valueExpr(typ, "params."+field)+")")
+		path = path[end+1:]
+	}
+	return strings.Join(parts, " + ")
+}
+
+func (g *generator) queryAndHeaders(params []param) bool {
+	m := &g.methods
+	var hasQuery, hasHeader bool
+	for _, p := range params {
+		hasQuery = hasQuery || p.In == "query"
+		hasHeader = hasHeader || p.In == "header"
+	}
+	if hasQuery {
+		g.imports["net/url"] = true
+		m.WriteString("query := url.Values{}\n")
+		for _, p := range params {
+			if p.In == "query" {
+				g.setValue(p, "query.Add")
+			}
+		}
+		m.WriteString("if len(query) > 0 {\npath += \"?\" + query.Encode()\n}\n")
+	}
+	if hasHeader {
+		m.WriteString("headers := map[string]string{}\n")
+		for _, p := range params {
+			if p.In == "header" {
+				g.setValue(p, "headers[%q] = ")
+			}
+		}
+		m.WriteString("ctx = client.ContextWithHeaders(ctx, headers)\n")
+	}
+	return hasQuery || hasHeader
+}
+
+func (g *generator) setValue(p param, setter string) {
+	m := &g.methods
+	assign := func(typ, value string) string {
+		value = g.valueExpr(typ, value)
+		if strings.HasPrefix(setter, "headers") {
+			return fmt.Sprintf(setter+"%s\n", p.Name, value)
+		}
+		return fmt.Sprintf("%s(%q, %s)\n", setter, p.Name, value)
+	}
+	switch {
+	case p.typ == "[]byte":
+		fmt.Fprintf(m, "if params.%s != nil {\n%s}\n", p.field, assign(p.typ, "params."+p.field))
+	case strings.HasPrefix(p.typ, "[]"):
+		fmt.Fprintf(m, "for _, v := range params.%s {\n%s}\n", p.field, assign(p.typ[2:], "v"))
+	case strings.HasPrefix(p.typ, "*"):
+		fmt.Fprintf(m, "if params.%s != nil {\n%s}\n", p.field, assign(p.typ[1:], "*params."+p.field))
+	default:
+		m.WriteString(assign(p.typ, "params."+p.field))
+	}
+}
+
+// valueExpr renders a parameter the way its JSON form reads: RFC 3339
+// timestamps and standard base64 bytes.
+func (g *generator) valueExpr(typ, value string) string {
+	switch typ {
+	case "time.Time":
+		if strings.HasPrefix(value, "*") {
+			value = "(" + value + ")"
+		}
+		return value + ".Format(time.RFC3339Nano)"
+	case "[]byte":
+		g.imports["encoding/base64"] = true
+		return "base64.StdEncoding.EncodeToString(" + value + ")"
+	}
+	return "fmt.Sprint(" + value + ")"
+}
+
+func statusSuffix(status string) string {
+	switch status {
+	case "default":
+		return "Default"
+	case "1XX", "2XX", "3XX":
+		return "Status" + status[:1] + "xx"
+	case "4XX":
+		return "Client"
+	case "5XX":
+		return "Server"
+	}
+	code, _ := strconv.Atoi(status)
+	if text := http.StatusText(code); text != "" {
+		return goName(text)
+	}
+	return "Status" + status
+}
+
+func (g *generator) responseCase(m *bytes.Buffer, name, opID, status string, res *response, resultType, zero string) {
+	s := jsonSchema(res.Content)
+	if strings.HasPrefix(status, "2") {
+		if resultType == "" {
+			m.WriteString("return nil\n")
+			return
+		}
+		if s == nil || g.goType(s, name+"Response") != resultType {
+			m.WriteString("return nil, nil\n")
+			return
+		}
+		g.imports["encoding/json"] = true
+		fmt.Fprintf(m, "if len(res.Body) == 0 {\nreturn nil, nil\n}\nvar out %s\n", resultType)
+		m.WriteString("if err := json.Unmarshal(res.Body, &out); err != nil {\nreturn nil, fail(span, err)\n}\n")
+		if resultRef(resultType) == resultType {
+			m.WriteString("return out, nil\n")
+		} else {
+			m.WriteString("return &out, nil\n")
+		}
+		return
+	}
+
+	errType := name + strings.TrimSuffix(statusSuffix(status), "Error") + "Error"
+	if !g.defined[errType] {
+		g.defined[errType] = true
+		comment(&g.types, fmt.Sprintf("%s is returned by %s for a %s response: %s", errType, name, status, strings.TrimSpace(res.Description)))
+		fmt.Fprintf(&g.types, "type %s struct {\nStatusCode int\n", errType)
+		if s != nil {
+			fmt.Fprintf(&g.types, "Body %s\n", g.goType(s, errType+"Body"))
+		}
+		g.types.WriteString("}\n\n")
+		description := strings.ToLower(strings.TrimSpace(strings.Split(res.Description, "\n")[0]))
+		message := fmt.Sprintf("%s: %s (status %%d)", opID, strings.ReplaceAll(description, "%", "%%"))
+		fmt.Fprintf(&g.types, "func (e *%s) Error() string {\nreturn fmt.Sprintf(%q, e.StatusCode)\n}\n\n", errType, message)
+	}
+	fmt.Fprintf(m, "apiErr := &%s{StatusCode: res.StatusCode}\n", errType)
+	if s != nil {
+		g.imports["encoding/json"] = true
+		m.WriteString("if err := json.Unmarshal(res.Body, &apiErr.Body); err != nil {\n")
+		fmt.Fprintf(m, "return %sfail(span, &UnexpectedStatusError{Operation: %q, StatusCode: res.StatusCode, Body: res.Body})\n}\n", zero, opID)
+	}
+	fmt.Fprintf(m, "return %sfail(span, apiErr)\n", zero)
+}
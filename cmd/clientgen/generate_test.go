@@ -0,0 +1,268 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/nphiro/mesh/cmd/clientgen/internal/petstore"
+	"github.com/nphiro/mesh/pkg/client"
+)
+
+var update = flag.Bool("update", false, "rewrite the checked-in petstore client")
+
+func TestGoName(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"listPets", "ListPets"},
+		{"pet_id", "PetID"},
+		{"X-Tenant-ID", "XTenantID"},
+		{"get /users/{userId}", "GetUsersUserID"},
+		{"httpURL", "HTTPURL"},
+		{"hamster-like", "HamsterLike"},
+		{"2fa", "X2fa"},
+		{"", "X"},
+	}
+	for _, tt := range tests {
+		if got := goName(tt.in); got != tt.want {
+			t.Errorf("goName(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestGeneratePetstore(t *testing.T) {
+	dir := filepath.Join("internal", "petstore")
+	doc, err := loadDocument(filepath.Join(dir, "api.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := generate(doc, "petstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "client.gen.go")
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("%s is stale; run go generate ./cmd/clientgen/... or go test ./cmd/clientgen -update", path)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	tests := []struct {
+		name    string
+		spec    string
+		want    []string
+		notWant []string
+		wantErr string
+	}{
+		{
+			name: "optional fields are pointers",
+			spec: `{"openapi":"3.1.0","components":{"schemas":{"User":{"type":"object","required":["id"],
+				"properties":{"id":{"type":"integer"},"nick":{"type":["string","null"]},"tags":{"type":"array","items":{"type":"string"}}}}}}}`,
+			want: []string{"ID   int64    `json:\"id\"`", "Nick *string  `json:\"nick,omitempty\"`", "Tags []string `json:\"tags,omitempty\"`"},
+		},
+		{
+			name: "operation without operationId",
+			spec: `{"openapi":"3.0.0","paths":{"/health":{"get":{"responses":{"204":{"description":"Healthy."}}}}}}`,
+			want: []string{"func (c *Client) GetHealth(ctx context.Context) error {", `Start(ctx, "Get /health"`},
+		},
+		{
+			name: "request body is dropped from DELETE",
+			spec: `{"openapi":"3.0.0","paths":{"/x":{"delete":{"operationId":"purge",
+				"requestBody":{"content":{"application/json":{"schema":{"type":"string"}}}},"responses":{"204":{"description":"Done."}}}}}}`,
+			want:    []string{"func (c *Client) Purge(ctx context.Context) error {"},
+			notWant: []string{"body string"},
+		},
+		{
+			name: "operation parameters override path parameters",
+			spec: `{"openapi":"3.0.0","paths":{"/x/{id}":{"parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"string"}}],
+				"get":{"operationId":"getX","parameters":[{"name":"id","in":"path","required":true,"schema":{"type":"integer"}},
+				{"name":"session","in":"cookie","schema":{"type":"string"}}],"responses":{"204":{"description":"Done."}}}}}}`,
+			want:    []string{"ID int64\n}"},
+			notWant: []string{"ID string", "Session"},
+		},
+		{
+			name: "time and byte parameters",
+			spec: `{"openapi":"3.0.0","paths":{"/days/{day}":{"get":{"operationId":"getDay","parameters":[
+				{"name":"day","in":"path","required":true,"schema":{"type":"string","format":"date-time"}},
+				{"name":"since","in":"query","schema":{"type":"string","format":"date-time"}},
+				{"name":"at","in":"query","schema":{"type":"array","items":{"type":"string","format":"date-time"}}},
+				{"name":"cursor","in":"query","schema":{"type":"string","format":"byte"}},
+				{"name":"X-Signature","in":"header","required":true,"schema":{"type":"string","format":"byte"}}],
+				"responses":{"204":{"description":"Done."}}}}}}`,
+			want: []string{
+				`"/days/" + url.PathEscape(params.Day.Format(time.RFC3339Nano))`,
+				`query.Add("since", (*params.Since).Format(time.RFC3339Nano))`,
+				`query.Add("at", v.Format(time.RFC3339Nano))`,
+				"if params.Cursor != nil {\n\t\tquery.Add(\"cursor\", base64.StdEncoding.EncodeToString(params.Cursor))",
+				`headers["X-Signature"] = base64.StdEncoding.EncodeToString(params.XSignature)`,
+			},
+			notWant: []string{"fmt.Sprint("},
+		},
+		{
+			name:    "unresolved reference",
+			spec:    `{"openapi":"3.0.0","paths":{"/x":{"get":{"parameters":[{"$ref":"#/components/parameters/Missing"}],"responses":{}}}}}`,
+			wantErr: `GET /x: unresolved reference "#/components/parameters/Missing"`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "api.json")
+			if err := os.WriteFile(path, []byte(tt.spec), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			doc, err := loadDocument(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			src, err := generate(doc, "api")
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("generate error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(src), want) {
+					t.Errorf("generated source lacks %q:\n%s", want, src)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(string(src), notWant) {
+					t.Errorf("generated source contains %q:\n%s", notWant, src)
+				}
+			}
+		})
+	}
+}
+
+func TestLoadDocumentRejectsSwagger2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "api.yaml")
+	if err := os.WriteFile(path, []byte("swagger: \"2.0\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadDocument(path); err == nil {
+		t.Fatal("loadDocument accepted a Swagger 2.0 document")
+	}
+}
+
+// TestGeneratedClient runs the checked-in petstore client against a fake API.
+func TestGeneratedClient(t *testing.T) {
+	var last *http.Request
+	var lastBody []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		last = r
+		lastBody, _ = io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/pets":
+			_, _ = w.Write([]byte(`[{"id":"p1","name":"Rex","kind":"dog","owner":{"name":"Ann"}}]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/pets":
+			if strings.Contains(string(lastBody), "Taken") {
+				w.WriteHeader(http.StatusConflict)
+				_, _ = w.Write([]byte(`{"status":409,"detail":"name taken"}`))
+				return
+			}
+			if strings.Contains(string(lastBody), "Bad") {
+				w.WriteHeader(http.StatusUnprocessableEntity)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write(bytes.Replace(lastBody, []byte("{"), []byte(`{"id":"p2",`), 1))
+		case r.Method == http.MethodGet:
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte(`{"status":500}`))
+		}
+	}))
+	defer srv.Close()
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pets := petstore.New(c)
+	ctx := context.Background()
+
+	limit := int32(5)
+	bornAfter := time.Date(2024, 1, 2, 3, 4, 5, 500_000_000, time.UTC)
+	list, err := pets.ListPets(ctx, petstore.ListPetsParams{
+		Limit:     &limit,
+		Tag:       []string{"a", "b"},
+		BornAfter: &bornAfter,
+		Cursor:    []byte{0xfb, 0xff},
+		XTenantID: "t1",
+	})
+	if err != nil {
+		t.Fatalf("ListPets: %v", err)
+	}
+	if len(list) != 1 || list[0].Kind != petstore.KindDog || list[0].Owner == nil || *list[0].Owner.Name != "Ann" {
+		t.Errorf("ListPets = %+v", list)
+	}
+	if got := last.URL.RawQuery; got != "bornAfter=2024-01-02T03%3A04%3A05.5Z&cursor=%2B%2F8%3D&limit=5&tag=a&tag=b" {
+		t.Errorf("query = %q", got)
+	}
+	if got := last.Header.Get("X-Tenant-ID"); got != "t1" {
+		t.Errorf("X-Tenant-ID = %q", got)
+	}
+
+	created, err := pets.CreatePet(ctx, petstore.CreatePetParams{XTenantID: "t1"}, petstore.NewPet{Name: "Tom", Kind: petstore.KindCat})
+	if err != nil {
+		t.Fatalf("CreatePet: %v", err)
+	}
+	if created.ID != "p2" || created.Name != "Tom" {
+		t.Errorf("CreatePet = %+v", created)
+	}
+	var sent map[string]any
+	if err := json.Unmarshal(lastBody, &sent); err != nil || sent["kind"] != "cat" || sent["birthday"] != nil {
+		t.Errorf("request body = %s", lastBody)
+	}
+
+	_, err = pets.CreatePet(ctx, petstore.CreatePetParams{}, petstore.NewPet{Name: "Taken"})
+	var conflict *petstore.CreatePetConflictError
+	if !errors.As(err, &conflict) || conflict.Body.Detail == nil || *conflict.Body.Detail != "name taken" {
+		t.Errorf("CreatePet conflict error = %v", err)
+	}
+	_, err = pets.CreatePet(ctx, petstore.CreatePetParams{}, petstore.NewPet{Name: "Bad"})
+	var invalid *petstore.CreatePetClientError
+	if !errors.As(err, &invalid) || invalid.StatusCode != http.StatusUnprocessableEntity {
+		t.Errorf("CreatePet 4XX error = %v", err)
+	}
+
+	_, err = pets.GetPet(ctx, petstore.GetPetParams{PetID: "a/b c"})
+	var notFound *petstore.GetPetNotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("GetPet error = %v, want GetPetNotFoundError", err)
+	}
+	if got := last.URL.EscapedPath(); got != "/pets/a%2Fb%20c" {
+		t.Errorf("path = %q", got)
+	}
+
+	err = pets.DeletePet(ctx, petstore.DeletePetParams{PetID: "p1"})
+	var unexpected *petstore.UnexpectedStatusError
+	if !errors.As(err, &unexpected) || unexpected.StatusCode != http.StatusInternalServerError || string(unexpected.Body) != `{"status":500}` {
+		t.Errorf("DeletePet error = %v, want UnexpectedStatusError", err)
+	}
+}
@@ -0,0 +1,279 @@
+// Code generated by clientgen. DO NOT EDIT.
+
+package petstore
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/nphiro/mesh/pkg/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "mesh.clientgen/petstore"
+
+type Client struct {
+	client client.Client
+}
+
+func New(c client.Client) *Client {
+	return &Client{client: c}
+}
+
+// UnexpectedStatusError is returned for responses the API document does not describe.
+type UnexpectedStatusError struct {
+	Operation  string
+	StatusCode int
+	Body       []byte
+}
+
+func (e *UnexpectedStatusError) Error() string {
+	return fmt.Sprintf("%s: unexpected status %d", e.Operation, e.StatusCode)
+}
+
+func fail(span trace.Span, err error) error {
+	span.RecordError(err)
+	span.SetStatus(codes.Error, err.Error())
+	return err
+}
+
+type Kind string
+
+const (
+	KindDog         Kind = "dog"
+	KindCat         Kind = "cat"
+	KindHamsterLike Kind = "hamster-like"
+)
+
+type NewPet struct {
+	Birthday *time.Time        `json:"birthday,omitempty"`
+	Kind     Kind              `json:"kind"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Name     string            `json:"name"`
+}
+
+type PetOwner struct {
+	Name *string `json:"name,omitempty"`
+}
+
+// A pet in the store.
+type Pet struct {
+	Birthday *time.Time        `json:"birthday,omitempty"`
+	Kind     Kind              `json:"kind"`
+	Labels   map[string]string `json:"labels,omitempty"`
+	Name     string            `json:"name"`
+	Extra    json.RawMessage   `json:"extra,omitempty"`
+	ID       string            `json:"id"`
+	Owner    *PetOwner         `json:"owner,omitempty"`
+}
+
+type Problem struct {
+	Detail *string `json:"detail,omitempty"`
+	Status int64   `json:"status"`
+}
+
+type ListPetsParams struct {
+	Limit *int32
+	// Only pets with all of the tags.
+	Tag       []string
+	BornAfter *time.Time
+	// Opaque cursor from the previous page.
+	Cursor    []byte
+	XTenantID string
+}
+
+// ListPetsDefaultError is returned by ListPets for a default response: Unexpected error.
+type ListPetsDefaultError struct {
+	StatusCode int
+	Body       Problem
+}
+
+func (e *ListPetsDefaultError) Error() string {
+	return fmt.Sprintf("listPets: unexpected error. (status %d)", e.StatusCode)
+}
+
+type CreatePetParams struct {
+	XTenantID string
+}
+
+// CreatePetConflictError is returned by CreatePet for a 409 response: A pet with the name exists.
+type CreatePetConflictError struct {
+	StatusCode int
+	Body       Problem
+}
+
+func (e *CreatePetConflictError) Error() string {
+	return fmt.Sprintf("createPet: a pet with the name exists. (status %d)", e.StatusCode)
+}
+
+// CreatePetClientError is returned by CreatePet for a 4XX response: Invalid pet.
+type CreatePetClientError struct {
+	StatusCode int
+}
+
+func (e *CreatePetClientError) Error() string {
+	return fmt.Sprintf("createPet: invalid pet. (status %d)", e.StatusCode)
+}
+
+type GetPetParams struct {
+	PetID string
+}
+
+// GetPetNotFoundError is returned by GetPet for a 404 response: No such pet.
+type GetPetNotFoundError struct {
+	StatusCode int
+}
+
+func (e *GetPetNotFoundError) Error() string {
+	return fmt.Sprintf("getPet: no such pet. (status %d)", e.StatusCode)
+}
+
+type DeletePetParams struct {
+	PetID string
+}
+
+// List pets, newest first.
+func (c *Client) ListPets(ctx context.Context, params ListPetsParams) ([]Pet, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "listPets", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	path := "/pets"
+	query := url.Values{}
+	if params.Limit != nil {
+		query.Add("limit", fmt.Sprint(*params.Limit))
+	}
+	for _, v := range params.Tag {
+		query.Add("tag", fmt.Sprint(v))
+	}
+	if params.BornAfter != nil {
+		query.Add("bornAfter", (*params.BornAfter).Format(time.RFC3339Nano))
+	}
+	if params.Cursor != nil {
+		query.Add("cursor", base64.StdEncoding.EncodeToString(params.Cursor))
+	}
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	headers := map[string]string{}
+	headers["X-Tenant-ID"] = fmt.Sprint(params.XTenantID)
+	ctx = client.ContextWithHeaders(ctx, headers)
+
+	res, err := c.client.Get(ctx, path)
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+	switch res.StatusCode {
+	case 200:
+		if len(res.Body) == 0 {
+			return nil, nil
+		}
+		var out []Pet
+		if err := json.Unmarshal(res.Body, &out); err != nil {
+			return nil, fail(span, err)
+		}
+		return out, nil
+	}
+	apiErr := &ListPetsDefaultError{StatusCode: res.StatusCode}
+	if err := json.Unmarshal(res.Body, &apiErr.Body); err != nil {
+		return nil, fail(span, &UnexpectedStatusError{Operation: "listPets", StatusCode: res.StatusCode, Body: res.Body})
+	}
+	return nil, fail(span, apiErr)
+}
+
+// Create a pet.
+func (c *Client) CreatePet(ctx context.Context, params CreatePetParams, body NewPet) (*Pet, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "createPet", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	path := "/pets"
+	headers := map[string]string{}
+	headers["X-Tenant-ID"] = fmt.Sprint(params.XTenantID)
+	ctx = client.ContextWithHeaders(ctx, headers)
+
+	res, err := c.client.Post(ctx, path, body)
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+	switch res.StatusCode {
+	case 201:
+		if len(res.Body) == 0 {
+			return nil, nil
+		}
+		var out Pet
+		if err := json.Unmarshal(res.Body, &out); err != nil {
+			return nil, fail(span, err)
+		}
+		return &out, nil
+	case 409:
+		apiErr := &CreatePetConflictError{StatusCode: res.StatusCode}
+		if err := json.Unmarshal(res.Body, &apiErr.Body); err != nil {
+			return nil, fail(span, &UnexpectedStatusError{Operation: "createPet", StatusCode: res.StatusCode, Body: res.Body})
+		}
+		return nil, fail(span, apiErr)
+	}
+	if res.StatusCode/100 == 4 {
+		apiErr := &CreatePetClientError{StatusCode: res.StatusCode}
+		return nil, fail(span, apiErr)
+	}
+	return nil, fail(span, &UnexpectedStatusError{Operation: "createPet", StatusCode: res.StatusCode, Body: res.Body})
+}
+
+func (c *Client) GetPet(ctx context.Context, params GetPetParams) (*Pet, error) {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "getPet", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	path := "/pets/" + url.PathEscape(fmt.Sprint(params.PetID))
+	res, err := c.client.Get(ctx, path)
+	if err != nil {
+		return nil, fail(span, err)
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+	switch res.StatusCode {
+	case 200:
+		if len(res.Body) == 0 {
+			return nil, nil
+		}
+		var out Pet
+		if err := json.Unmarshal(res.Body, &out); err != nil {
+			return nil, fail(span, err)
+		}
+		return &out, nil
+	case 404:
+		apiErr := &GetPetNotFoundError{StatusCode: res.StatusCode}
+		return nil, fail(span, apiErr)
+	}
+	return nil, fail(span, &UnexpectedStatusError{Operation: "getPet", StatusCode: res.StatusCode, Body: res.Body})
+}
+
+// Deletes the pet for good.
+//
+// Deprecated: the API document marks this operation as deprecated.
+func (c *Client) DeletePet(ctx context.Context, params DeletePetParams) error {
+	ctx, span := otel.Tracer(tracerName).Start(ctx, "deletePet", trace.WithSpanKind(trace.SpanKindClient))
+	defer span.End()
+
+	path := "/pets/" + url.PathEscape(fmt.Sprint(params.PetID))
+	res, err := c.client.Delete(ctx, path)
+	if err != nil {
+		return fail(span, err)
+	}
+	span.SetAttributes(attribute.Int("http.response.status_code", res.StatusCode))
+
+	switch res.StatusCode {
+	case 204:
+		return nil
+	}
+	return fail(span, &UnexpectedStatusError{Operation: "deletePet", StatusCode: res.StatusCode, Body: res.Body})
+}
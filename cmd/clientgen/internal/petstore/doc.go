@@ -0,0 +1,6 @@
+// Package petstore is clientgen's output for api.yaml. It is checked in so the
+// build catches generated code that does not compile, and the clientgen tests
+// fail when it is stale.
+package petstore
+
+//go:generate go run github.com/nphiro/mesh/cmd/clientgen -spec api.yaml -out client.gen.go
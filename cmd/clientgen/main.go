@@ -0,0 +1,51 @@
+// Clientgen generates a typed client package from an OpenAPI 3 document,
+// using pkg/client for transport:
+//
+//	//go:generate go run github.com/nphiro/mesh/cmd/clientgen -spec api.yaml -out client.gen.go
+package main
+
+import (
+	"flag"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	var (
+		specPath = flag.String("spec", "", "path to the OpenAPI 3 document (JSON or YAML)")
+		pkgName  = flag.String("package", "", "name of the generated package (defaults to the output directory name)")
+		outPath  = flag.String("out", "client.gen.go", "path of the generated Go file")
+	)
+	flag.Parse()
+
+	if *specPath == "" {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if *pkgName == "" {
+		abs, err := filepath.Abs(filepath.Dir(*outPath))
+		if err != nil {
+			slog.Error("Failed to resolve output directory", slog.Any("error", err))
+			os.Exit(1)
+		}
+		*pkgName = strings.ToLower(strings.NewReplacer("-", "", "_", "", ".", "").Replace(filepath.Base(abs)))
+	}
+
+	doc, err := loadDocument(*specPath)
+	if err != nil {
+		slog.Error("Failed to load API document", slog.Any("error", err))
+		os.Exit(1)
+	}
+	src, err := generate(doc, *pkgName)
+	if err != nil {
+		slog.Error("Failed to generate client", slog.Any("error", err))
+		os.Exit(1)
+	}
+	if err := os.WriteFile(*outPath, src, 0o644); err != nil {
+		slog.Error("Failed to write generated client", slog.Any("error", err))
+		os.Exit(1)
+	}
+	slog.Info("Generated client", slog.String("package", *pkgName), slog.String("file", *outPath))
+}
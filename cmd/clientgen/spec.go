@@ -0,0 +1,195 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+type document struct {
+	OpenAPI    string              `json:"openapi"`
+	Paths      map[string]pathItem `json:"paths"`
+	Components components          `json:"components"`
+}
+
+type components struct {
+	Schemas       map[string]*schema      `json:"schemas"`
+	Parameters    map[string]*parameter   `json:"parameters"`
+	RequestBodies map[string]*requestBody `json:"requestBodies"`
+	Responses     map[string]*response    `json:"responses"`
+}
+
+type pathItem struct {
+	Parameters []*parameter `json:"parameters"`
+	Get        *operation   `json:"get"`
+	Put        *operation   `json:"put"`
+	Post       *operation   `json:"post"`
+	Delete     *operation   `json:"delete"`
+	Options    *operation   `json:"options"`
+	Head       *operation   `json:"head"`
+	Patch      *operation   `json:"patch"`
+}
+
+func (p pathItem) operations() []struct {
+	method string
+	op     *operation
+} {
+	all := []struct {
+		method string
+		op     *operation
+	}{
+		{"Get", p.Get}, {"Put", p.Put}, {"Post", p.Post}, {"Delete", p.Delete},
+		{"Options", p.Options}, {"Head", p.Head}, {"Patch", p.Patch},
+	}
+	ops := all[:0]
+	for _, o := range all {
+		if o.op != nil {
+			ops = append(ops, o)
+		}
+	}
+	return ops
+}
+
+type operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary"`
+	Description string               `json:"description"`
+	Deprecated  bool                 `json:"deprecated"`
+	Parameters  []*parameter         `json:"parameters"`
+	RequestBody *requestBody         `json:"requestBody"`
+	Responses   map[string]*response `json:"responses"`
+}
+
+type parameter struct {
+	Ref         string  `json:"$ref"`
+	Name        string  `json:"name"`
+	In          string  `json:"in"`
+	Description string  `json:"description"`
+	Required    bool    `json:"required"`
+	Schema      *schema `json:"schema"`
+}
+
+type requestBody struct {
+	Ref      string               `json:"$ref"`
+	Required bool                 `json:"required"`
+	Content  map[string]mediaType `json:"content"`
+}
+
+type response struct {
+	Ref         string               `json:"$ref"`
+	Description string               `json:"description"`
+	Content     map[string]mediaType `json:"content"`
+}
+
+type mediaType struct {
+	Schema *schema `json:"schema"`
+}
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 any                `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Nullable             bool               `json:"nullable"`
+	Required             []string           `json:"required"`
+	Properties           map[string]*schema `json:"properties"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties any                `json:"additionalProperties"`
+	AllOf                []*schema          `json:"allOf"`
+	OneOf                []*schema          `json:"oneOf"`
+	AnyOf                []*schema          `json:"anyOf"`
+	Enum                 []any              `json:"enum"`
+}
+
+// typeName returns the primary type, accepting both the 3.0 string form
+// and the 3.1 array form (e.g. ["string", "null"]).
+func (s *schema) typeName() string {
+	switch t := s.Type.(type) {
+	case string:
+		return t
+	case []any:
+		for _, v := range t {
+			if v, ok := v.(string); ok && v != "null" {
+				return v
+			}
+		}
+	}
+	return ""
+}
+
+func loadDocument(path string) (*document, error) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".yaml", ".yml":
+		var raw any
+		if err := yaml.Unmarshal(b, &raw); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+		if b, err = json.Marshal(raw); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", path, err)
+		}
+	}
+	var doc document
+	if err := json.Unmarshal(b, &doc); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if !strings.HasPrefix(doc.OpenAPI, "3.") {
+		return nil, fmt.Errorf("unsupported openapi version %q", doc.OpenAPI)
+	}
+	return &doc, nil
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func (d *document) parameter(p *parameter) (*parameter, error) {
+	if p.Ref == "" {
+		return p, nil
+	}
+	resolved, ok := d.Components.Parameters[refName(p.Ref)]
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference %q", p.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *document) requestBody(b *requestBody) (*requestBody, error) {
+	if b.Ref == "" {
+		return b, nil
+	}
+	resolved, ok := d.Components.RequestBodies[refName(b.Ref)]
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference %q", b.Ref)
+	}
+	return resolved, nil
+}
+
+func (d *document) response(r *response) (*response, error) {
+	if r.Ref == "" {
+		return r, nil
+	}
+	resolved, ok := d.Components.Responses[refName(r.Ref)]
+	if !ok {
+		return nil, fmt.Errorf("unresolved reference %q", r.Ref)
+	}
+	return resolved, nil
+}
+
+func jsonSchema(content map[string]mediaType) *schema {
+	for _, contentType := range slices.Sorted(maps.Keys(content)) {
+		if strings.HasPrefix(contentType, "application/json") || strings.HasSuffix(contentType, "+json") {
+			return content[contentType].Schema
+		}
+	}
+	return nil
+}
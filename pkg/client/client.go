@@ -4,12 +4,14 @@ import (
 	"context"
 	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"time"
 
 	"github.com/gofiber/fiber/v3"
 	fclient "github.com/gofiber/fiber/v3/client"
+	"github.com/valyala/fasthttp"
 )
 
 type Client interface {
@@ -41,7 +43,8 @@ func New(baseUrl string, opts ...Option) (Client, error) {
 			return nil, c.error
 		}
 	}
-	app := fclient.New()
+	// Escaped path segments such as %2F in IDs must reach the server as sent.
+	app := fclient.NewWithClient(&fasthttp.Client{DisablePathNormalizing: true})
 	app.SetBaseURL(baseUrl)
 	if len(c.certificates) > 0 {
 		app.SetTLSConfig(&tls.Config{
@@ -54,24 +57,44 @@ func New(baseUrl string, opts ...Option) (Client, error) {
 	if c.proxy != "" {
 		app.SetProxyURL(c.proxy)
 	}
+	if c.retry != nil {
+		app.SetRetryConfig(c.retry)
+	}
+	if c.timeout > 0 {
+		app.SetTimeout(c.timeout)
+	}
 	app.AddRequestHook(func(c *fclient.Client, req *fclient.Request) error {
 		req.SetContext(context.WithValue(req.Context(), requestTimeKey, time.Now()))
 		return nil
 	})
+	app.AddRequestHook(setContextHeaders)
+	app.AddRequestHook(forwardRequestID)
+	app.AddRequestHook(applyDeadline(c.timeout, c.propagateDeadline))
+	if c.idempotencyKey {
+		app.AddRequestHook(setIdempotencyKey)
+	}
+	if c.cookieJar != nil {
+		app.AddRequestHook(c.cookieJar.requestHook)
+		app.AddResponseHook(c.cookieJar.responseHook)
+	}
 	app.AddResponseHook(func(_ *fclient.Client, res *fclient.Response, req *fclient.Request) error {
 		if !c.requestLog {
 			return nil
 		}
 		ctx := req.Context()
 		latency := time.Since(ctx.Value(requestTimeKey).(time.Time))
-		slog.InfoContext(ctx, "request",
+		attrs := []any{
 			slog.String("request_url", req.RawRequest.URI().String()),
 			slog.String("request_method", req.Method()),
 			slog.String("request_body", string(req.RawRequest.Body())),
 			slog.String("response_body", string(res.Body())),
 			slog.Int("response_status", res.StatusCode()),
 			slog.Int64("latency_ms", latency.Milliseconds()),
-		)
+		}
+		if key, ok := IdempotencyKeyFromContext(ctx); ok {
+			attrs = append(attrs, slog.String("idempotency_key", key))
+		}
+		slog.InfoContext(ctx, "request", attrs...)
 		return nil
 	})
 	return &client{
@@ -102,6 +125,9 @@ func (c *client) request(ctx context.Context, body any) *fclient.Request {
 func (c *client) response(req *fclient.Request, method, path string) (*Response, error) {
 	res, err := req.SetMethod(method).SetURL(path).Send()
 	if err != nil {
+		if ctxErr := req.Context().Err(); ctxErr != nil && errors.Is(err, fclient.ErrTimeoutOrCancel) {
+			return nil, fmt.Errorf("%w: %w", err, ctxErr)
+		}
 		return nil, err
 	}
 	defer res.Close()
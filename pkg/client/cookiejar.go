@@ -0,0 +1,288 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"maps"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	fclient "github.com/gofiber/fiber/v3/client"
+	"golang.org/x/net/publicsuffix"
+)
+
+type Cookie struct {
+	Name     string    `json:"name"`
+	Value    string    `json:"value"`
+	Domain   string    `json:"domain"`
+	Path     string    `json:"path"`
+	HostOnly bool      `json:"host_only,omitempty"`
+	Secure   bool      `json:"secure,omitempty"`
+	HttpOnly bool      `json:"http_only,omitempty"`
+	Expires  time.Time `json:"expires,omitzero"`
+}
+
+func (c *Cookie) key() string {
+	return c.Domain + ";" + c.Path + ";" + c.Name
+}
+
+func (c *Cookie) expired(now time.Time) bool {
+	return !c.Expires.IsZero() && !c.Expires.After(now)
+}
+
+type CookieStore interface {
+	Load() ([]Cookie, error)
+	Save(cookies []Cookie) error
+}
+
+// CookieJar keeps cookies scoped by domain and path as described in RFC 6265.
+// One jar may be passed to several clients to share a session between them.
+type CookieJar struct {
+	mu      sync.Mutex
+	cookies map[string]Cookie
+	store   CookieStore
+}
+
+func NewCookieJar(store CookieStore) (*CookieJar, error) {
+	if store == nil {
+		store = NewMemoryCookieStore()
+	}
+	cookies, err := store.Load()
+	if err != nil {
+		return nil, err
+	}
+	jar := &CookieJar{
+		cookies: map[string]Cookie{},
+		store:   store,
+	}
+	now := time.Now()
+	for _, cookie := range cookies {
+		if !cookie.expired(now) {
+			jar.cookies[cookie.key()] = cookie
+		}
+	}
+	return jar, nil
+}
+
+func (j *CookieJar) Cookies(u *url.URL) []Cookie {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	host := canonicalHost(u.Host)
+	path := u.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	now := time.Now()
+	var matched []Cookie
+	for key, cookie := range j.cookies {
+		if cookie.expired(now) {
+			delete(j.cookies, key)
+			continue
+		}
+		if cookie.Secure && u.Scheme != "https" {
+			continue
+		}
+		if !cookie.domainMatch(host) || !pathMatch(path, cookie.Path) {
+			continue
+		}
+		matched = append(matched, cookie)
+	}
+	slices.SortFunc(matched, func(a, b Cookie) int {
+		return len(b.Path) - len(a.Path)
+	})
+	return matched
+}
+
+func (j *CookieJar) SetCookies(u *url.URL, cookies []*http.Cookie) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	host := canonicalHost(u.Host)
+	now := time.Now()
+	changed := false
+	for _, c := range cookies {
+		cookie := Cookie{
+			Name:     c.Name,
+			Value:    c.Value,
+			Domain:   strings.TrimPrefix(strings.ToLower(c.Domain), "."),
+			Path:     c.Path,
+			Secure:   c.Secure,
+			HttpOnly: c.HttpOnly,
+		}
+		if cookie.Domain != "" && isPublicSuffix(cookie.Domain) {
+			// A cookie for a public suffix such as com or co.uk would reach every
+			// site under it; RFC 6265 only allows it as a host cookie of that host.
+			if cookie.Domain != host {
+				continue
+			}
+			cookie.Domain = ""
+		}
+		if cookie.Domain == "" {
+			cookie.Domain, cookie.HostOnly = host, true
+		} else if !cookie.domainMatch(host) {
+			continue
+		}
+		if cookie.Path == "" || !strings.HasPrefix(cookie.Path, "/") {
+			cookie.Path = defaultPath(u.EscapedPath())
+		}
+		switch {
+		case c.MaxAge < 0:
+			cookie.Expires = now
+		case c.MaxAge > 0:
+			cookie.Expires = now.Add(time.Duration(c.MaxAge) * time.Second)
+		case !c.Expires.IsZero():
+			cookie.Expires = c.Expires
+		}
+		if cookie.expired(now) {
+			if _, ok := j.cookies[cookie.key()]; ok {
+				delete(j.cookies, cookie.key())
+				changed = true
+			}
+			continue
+		}
+		j.cookies[cookie.key()] = cookie
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return j.store.Save(slices.Collect(maps.Values(j.cookies)))
+}
+
+func (c *Cookie) domainMatch(host string) bool {
+	if c.HostOnly || net.ParseIP(host) != nil {
+		return host == c.Domain
+	}
+	return host == c.Domain || strings.HasSuffix(host, "."+c.Domain)
+}
+
+func isPublicSuffix(domain string) bool {
+	if net.ParseIP(domain) != nil {
+		return false
+	}
+	suffix, _ := publicsuffix.PublicSuffix(domain)
+	return suffix == domain
+}
+
+func pathMatch(requestPath, cookiePath string) bool {
+	if requestPath == cookiePath {
+		return true
+	}
+	if !strings.HasPrefix(requestPath, cookiePath) {
+		return false
+	}
+	return strings.HasSuffix(cookiePath, "/") || requestPath[len(cookiePath)] == '/'
+}
+
+func defaultPath(requestPath string) string {
+	i := strings.LastIndex(requestPath, "/")
+	if i <= 0 {
+		return "/"
+	}
+	return requestPath[:i]
+}
+
+func canonicalHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.Trim(host, "[]"))
+}
+
+func (j *CookieJar) requestHook(c *fclient.Client, req *fclient.Request) error {
+	rawURL := req.URL()
+	if !strings.HasPrefix(rawURL, "http://") && !strings.HasPrefix(rawURL, "https://") {
+		rawURL = c.BaseURL() + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return err
+	}
+	for _, cookie := range j.Cookies(u) {
+		req.SetCookie(cookie.Name, cookie.Value)
+	}
+	return nil
+}
+
+func (j *CookieJar) responseHook(_ *fclient.Client, res *fclient.Response, req *fclient.Request) error {
+	var cookies []*http.Cookie
+	res.RawResponse.Header.VisitAllCookie(func(_, value []byte) {
+		if cookie, err := http.ParseSetCookie(string(value)); err == nil {
+			cookies = append(cookies, cookie)
+		}
+	})
+	if len(cookies) == 0 {
+		return nil
+	}
+	u, err := url.Parse(req.RawRequest.URI().String())
+	if err != nil {
+		return err
+	}
+	return j.SetCookies(u, cookies)
+}
+
+type memoryCookieStore struct{}
+
+func NewMemoryCookieStore() CookieStore {
+	return memoryCookieStore{}
+}
+
+func (memoryCookieStore) Load() ([]Cookie, error) {
+	return nil, nil
+}
+
+func (memoryCookieStore) Save([]Cookie) error {
+	return nil
+}
+
+type fileCookieStore struct {
+	path string
+}
+
+// NewFileCookieStore persists cookies as JSON at path, including session cookies,
+// so a restarted process can resume a partner login.
+func NewFileCookieStore(path string) CookieStore {
+	return &fileCookieStore{path}
+}
+
+func (s *fileCookieStore) Load() ([]Cookie, error) {
+	b, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cookies []Cookie
+	if err := json.Unmarshal(b, &cookies); err != nil {
+		return nil, err
+	}
+	return cookies, nil
+}
+
+func (s *fileCookieStore) Save(cookies []Cookie) error {
+	b, err := json.Marshal(cookies)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), filepath.Base(s.path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(b); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,181 @@
+package client
+
+import (
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func mustParseURL(t *testing.T, raw string) *url.URL {
+	t.Helper()
+	u, err := url.Parse(raw)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return u
+}
+
+func TestCookieJarScoping(t *testing.T) {
+	tests := []struct {
+		name   string
+		setURL string
+		cookie http.Cookie
+		// sent and notSent are request URLs that must or must not carry the cookie.
+		sent    []string
+		notSent []string
+	}{
+		{
+			name:    "host only",
+			setURL:  "https://api.example.com/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/"},
+			sent:    []string{"https://api.example.com/", "https://API.example.com:8443/x"},
+			notSent: []string{"https://example.com/", "https://v2.api.example.com/"},
+		},
+		{
+			name:    "domain",
+			setURL:  "https://api.example.com/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Domain: ".Example.com"},
+			sent:    []string{"https://example.com/", "https://api.example.com/", "https://a.b.example.com/"},
+			notSent: []string{"https://notexample.com/", "https://example.com.evil.net/"},
+		},
+		{
+			name:    "foreign domain",
+			setURL:  "https://api.example.com/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Domain: "other.com"},
+			notSent: []string{"https://other.com/", "https://api.example.com/"},
+		},
+		{
+			name:    "public suffix",
+			setURL:  "https://api.example.com/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Domain: "com"},
+			notSent: []string{"https://api.example.com/", "https://other.com/"},
+		},
+		{
+			name:    "multi-label public suffix",
+			setURL:  "https://shop.example.co.uk/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Domain: "co.uk"},
+			notSent: []string{"https://shop.example.co.uk/", "https://other.co.uk/"},
+		},
+		{
+			name:    "public suffix host",
+			setURL:  "https://github.io/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Domain: "github.io"},
+			sent:    []string{"https://github.io/"},
+			notSent: []string{"https://someone.github.io/"},
+		},
+		{
+			name:    "ip address",
+			setURL:  "http://10.0.0.1:8080/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Domain: "10.0.0.1"},
+			sent:    []string{"http://10.0.0.1/"},
+			notSent: []string{"http://10.0.0.10/"},
+		},
+		{
+			name:    "secure",
+			setURL:  "https://example.com/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Secure: true},
+			sent:    []string{"https://example.com/"},
+			notSent: []string{"http://example.com/"},
+		},
+		{
+			name:    "path",
+			setURL:  "https://example.com/login",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/api"},
+			sent:    []string{"https://example.com/api", "https://example.com/api/v1"},
+			notSent: []string{"https://example.com/", "https://example.com/apix", "https://example.com/ap"},
+		},
+		{
+			name:    "default path",
+			setURL:  "https://example.com/account/login/form",
+			cookie:  http.Cookie{Name: "s", Value: "1"},
+			sent:    []string{"https://example.com/account/login", "https://example.com/account/login/next"},
+			notSent: []string{"https://example.com/account", "https://example.com/"},
+		},
+		{
+			name:    "expired",
+			setURL:  "https://example.com/",
+			cookie:  http.Cookie{Name: "s", Value: "1", Path: "/", Expires: time.Now().Add(-time.Hour)},
+			notSent: []string{"https://example.com/"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jar, err := NewCookieJar(nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := jar.SetCookies(mustParseURL(t, tt.setURL), []*http.Cookie{&tt.cookie}); err != nil {
+				t.Fatal(err)
+			}
+			for _, raw := range tt.sent {
+				if len(jar.Cookies(mustParseURL(t, raw))) != 1 {
+					t.Errorf("cookie not sent to %s", raw)
+				}
+			}
+			for _, raw := range tt.notSent {
+				if len(jar.Cookies(mustParseURL(t, raw))) != 0 {
+					t.Errorf("cookie sent to %s", raw)
+				}
+			}
+		})
+	}
+}
+
+func TestCookieJarOrderAndRemoval(t *testing.T) {
+	jar, err := NewCookieJar(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := mustParseURL(t, "https://example.com/api/v1/orders")
+	err = jar.SetCookies(u, []*http.Cookie{
+		{Name: "root", Value: "1", Path: "/"},
+		{Name: "api", Value: "2", Path: "/api"},
+		{Name: "v1", Value: "3", Path: "/api/v1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// More specific paths come first, as RFC 6265 recommends.
+	var names []string
+	for _, cookie := range jar.Cookies(u) {
+		names = append(names, cookie.Name)
+	}
+	if len(names) != 3 || names[0] != "v1" || names[1] != "api" || names[2] != "root" {
+		t.Errorf("cookies = %v, want [v1 api root]", names)
+	}
+
+	if err := jar.SetCookies(u, []*http.Cookie{{Name: "api", Path: "/api", MaxAge: -1}}); err != nil {
+		t.Fatal(err)
+	}
+	if n := len(jar.Cookies(u)); n != 2 {
+		t.Errorf("cookies after removal = %d, want 2", n)
+	}
+}
+
+func TestFileCookieStore(t *testing.T) {
+	store := NewFileCookieStore(filepath.Join(t.TempDir(), "cookies.json"))
+	jar, err := NewCookieJar(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	u := mustParseURL(t, "https://partner.example.com/login")
+	err = jar.SetCookies(u, []*http.Cookie{
+		{Name: "session", Value: "abc", Path: "/"},
+		{Name: "short", Value: "x", Path: "/", Expires: time.Now().Add(50 * time.Millisecond)},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(100 * time.Millisecond)
+
+	restored, err := NewCookieJar(store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cookies := restored.Cookies(u)
+	if len(cookies) != 1 || cookies[0].Name != "session" || cookies[0].Value != "abc" {
+		t.Errorf("restored cookies = %+v, want the session cookie only", cookies)
+	}
+}
@@ -0,0 +1,68 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	fclient "github.com/gofiber/fiber/v3/client"
+)
+
+// TimeoutHeader carries the caller's remaining budget in grpc-timeout
+// format (e.g. "150m" for 150 milliseconds).
+const TimeoutHeader = "X-Request-Timeout"
+
+// applyDeadline bounds the request by the sooner of the context deadline and
+// the request or client timeout, and optionally sends that budget upstream.
+func applyDeadline(clientTimeout time.Duration, propagate bool) fclient.RequestHook {
+	return func(_ *fclient.Client, req *fclient.Request) error {
+		timeout := req.Timeout()
+		if timeout <= 0 {
+			timeout = clientTimeout
+		}
+		if deadline, ok := req.Context().Deadline(); ok {
+			remaining := time.Until(deadline)
+			if remaining <= 0 {
+				return context.DeadlineExceeded
+			}
+			if timeout <= 0 || remaining < timeout {
+				timeout = remaining
+			}
+		}
+		if timeout <= 0 {
+			return nil
+		}
+		req.SetTimeout(timeout)
+		if propagate {
+			req.SetHeader(TimeoutHeader, encodeTimeout(timeout))
+		}
+		return nil
+	}
+}
+
+// grpc-timeout values have at most 8 digits.
+const maxTimeoutValue = 99_999_999
+
+var timeoutUnits = []struct {
+	unit   time.Duration
+	suffix string
+}{
+	{time.Millisecond, "m"},
+	{time.Second, "S"},
+	{time.Minute, "M"},
+	{time.Hour, "H"},
+}
+
+// encodeTimeout uses milliseconds where they fit, falling back to microseconds
+// for tiny budgets and truncating to coarser units for long ones.
+func encodeTimeout(d time.Duration) string {
+	if d < time.Millisecond {
+		return strconv.FormatInt(d.Microseconds(), 10) + "u"
+	}
+	for _, u := range timeoutUnits {
+		if n := int64(d / u.unit); n <= maxTimeoutValue {
+			return strconv.FormatInt(n, 10) + u.suffix
+		}
+	}
+	return strconv.Itoa(maxTimeoutValue) + "H"
+}
@@ -0,0 +1,35 @@
+package client
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestEncodeTimeout(t *testing.T) {
+	tests := []struct {
+		timeout time.Duration
+		want    string
+	}{
+		{0, "0u"},
+		{250 * time.Microsecond, "250u"},
+		{999 * time.Microsecond, "999u"},
+		{time.Millisecond, "1m"},
+		{1500 * time.Millisecond, "1500m"},
+		{99_999_999 * time.Millisecond, "99999999m"},
+		// One millisecond more rolls over to seconds, truncating.
+		{100_000_000 * time.Millisecond, "100000S"},
+		{99_999_999 * time.Second, "99999999S"},
+		{100_000_000 * time.Second, "1666666M"},
+		{99_999_999 * time.Minute, "99999999M"},
+		{100_000_000 * time.Minute, "1666666H"},
+		{math.MaxInt64, "2562047H"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.timeout.String(), func(t *testing.T) {
+			if got := encodeTimeout(tt.timeout); got != tt.want {
+				t.Errorf("encodeTimeout(%s) = %q, want %q", tt.timeout, got, tt.want)
+			}
+		})
+	}
+}
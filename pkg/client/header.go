@@ -0,0 +1,31 @@
+package client
+
+import (
+	"context"
+
+	fclient "github.com/gofiber/fiber/v3/client"
+)
+
+var headersContextKey contextKey = "headers"
+
+// ContextWithHeaders adds per-call headers to requests made with ctx,
+// on top of the client-wide headers set by WithHeaders.
+func ContextWithHeaders(ctx context.Context, headers map[string]string) context.Context {
+	merged := map[string]string{}
+	if parent, ok := ctx.Value(headersContextKey).(map[string]string); ok {
+		for k, v := range parent {
+			merged[k] = v
+		}
+	}
+	for k, v := range headers {
+		merged[k] = v
+	}
+	return context.WithValue(ctx, headersContextKey, merged)
+}
+
+func setContextHeaders(_ *fclient.Client, req *fclient.Request) error {
+	if headers, ok := req.Context().Value(headersContextKey).(map[string]string); ok {
+		req.SetHeaders(headers)
+	}
+	return nil
+}
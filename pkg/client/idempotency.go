@@ -0,0 +1,51 @@
+package client
+
+import (
+	"context"
+
+	"github.com/gofiber/fiber/v3"
+	fclient "github.com/gofiber/fiber/v3/client"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const idempotencyKeyHeader = "Idempotency-Key"
+
+var idempotencyKeyContextKey contextKey = "idempotency_key"
+
+// ContextWithIdempotencyKey pins the Idempotency-Key sent by requests made with ctx,
+// so a caller retrying the same logical operation can reuse it across calls:
+//
+//	ctx = client.ContextWithIdempotencyKey(ctx, uuid.NewString())
+//	for range 3 {
+//		resp, err = c.Post(ctx, "/payments", payment)
+//		...
+//	}
+func ContextWithIdempotencyKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, idempotencyKeyContextKey, key)
+}
+
+func IdempotencyKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(idempotencyKeyContextKey).(string)
+	return key, ok && key != ""
+}
+
+// setIdempotencyKey runs once per Send, before fiber's built-in retries,
+// so every retry attempt carries the same key.
+func setIdempotencyKey(_ *fclient.Client, req *fclient.Request) error {
+	ctx := req.Context()
+	key, ok := IdempotencyKeyFromContext(ctx)
+	if !ok {
+		switch req.Method() {
+		case fiber.MethodPost, fiber.MethodPatch:
+			key = uuid.NewString()
+		default:
+			return nil
+		}
+	}
+	req.SetHeader(idempotencyKeyHeader, key)
+	req.SetContext(ContextWithIdempotencyKey(ctx, key))
+	trace.SpanFromContext(ctx).SetAttributes(attribute.StringSlice("http.request.header.idempotency-key", []string{key}))
+	return nil
+}
@@ -0,0 +1,139 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// keyRecorder serves 201 after dropping the connection failures times,
+// recording the Idempotency-Key of every attempt.
+type keyRecorder struct {
+	failures int
+
+	mu   sync.Mutex
+	keys []string
+}
+
+func (k *keyRecorder) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	k.mu.Lock()
+	k.keys = append(k.keys, r.Header.Get(idempotencyKeyHeader))
+	fail := len(k.keys) <= k.failures
+	k.mu.Unlock()
+	if fail {
+		conn, _, err := w.(http.Hijacker).Hijack()
+		if err == nil {
+			conn.Close()
+		}
+		return
+	}
+	w.WriteHeader(http.StatusCreated)
+}
+
+func (k *keyRecorder) attempts() []string {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	return k.keys
+}
+
+func TestIdempotencyKeyStableAcrossRetries(t *testing.T) {
+	recorder := &keyRecorder{failures: 1}
+	srv := httptest.NewServer(recorder)
+	defer srv.Close()
+	c, err := New(srv.URL, WithIdempotencyKey(), WithRetry(2, time.Millisecond))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	res, err := c.Post(context.Background(), "/payments", map[string]int{"amount": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.StatusCode != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", res.StatusCode, http.StatusCreated)
+	}
+	keys := recorder.attempts()
+	if len(keys) != 2 {
+		t.Fatalf("attempts = %d, want 2", len(keys))
+	}
+	if keys[0] == "" || keys[1] != keys[0] {
+		t.Errorf("keys = %q, want one generated key on both attempts", keys)
+	}
+
+	// The next Send is another operation and gets a fresh key.
+	if _, err := c.Post(context.Background(), "/payments", map[string]int{"amount": 1}); err != nil {
+		t.Fatal(err)
+	}
+	if keys := recorder.attempts(); keys[2] == keys[0] {
+		t.Errorf("second call reused key %q", keys[2])
+	}
+}
+
+func TestIdempotencyKeyHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		method string
+		pinned string
+		// want is the expected key; "generated" accepts any non-empty key.
+		want string
+	}{
+		{"generated for POST", http.MethodPost, "", "generated"},
+		{"generated for PATCH", http.MethodPatch, "", "generated"},
+		{"not generated for PUT", http.MethodPut, "", ""},
+		{"not generated for GET", http.MethodGet, "", ""},
+		{"pinned key wins for POST", http.MethodPost, "pay-1", "pay-1"},
+		{"pinned key sent for PUT", http.MethodPut, "pay-1", "pay-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &keyRecorder{}
+			srv := httptest.NewServer(recorder)
+			defer srv.Close()
+			c, err := New(srv.URL, WithIdempotencyKey())
+			if err != nil {
+				t.Fatal(err)
+			}
+			spans := tracetest.NewSpanRecorder()
+			ctx, span := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(spans)).Tracer("test").Start(context.Background(), "call")
+			if tt.pinned != "" {
+				ctx = ContextWithIdempotencyKey(ctx, tt.pinned)
+			}
+
+			switch tt.method {
+			case http.MethodPost:
+				_, err = c.Post(ctx, "/payments", nil)
+			case http.MethodPatch:
+				_, err = c.Patch(ctx, "/payments/1", nil)
+			case http.MethodPut:
+				_, err = c.Put(ctx, "/payments/1", nil)
+			default:
+				_, err = c.Get(ctx, "/payments/1")
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			span.End()
+
+			got := recorder.attempts()[0]
+			if tt.want == "generated" && got == "" || tt.want != "generated" && got != tt.want {
+				t.Errorf("Idempotency-Key = %q, want %s", got, tt.want)
+			}
+			var attr []string
+			for _, kv := range spans.Ended()[0].Attributes() {
+				if kv.Key == "http.request.header.idempotency-key" && kv.Value.Type() == attribute.STRINGSLICE {
+					attr = kv.Value.AsStringSlice()
+				}
+			}
+			if got == "" && attr != nil || got != "" && (len(attr) != 1 || attr[0] != got) {
+				t.Errorf("span attribute = %q, want the sent key %q", attr, got)
+			}
+		})
+	}
+}
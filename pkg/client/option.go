@@ -2,6 +2,9 @@ package client
 
 import (
 	"crypto/tls"
+	"time"
+
+	fclient "github.com/gofiber/fiber/v3/client"
 )
 
 type Option func(c *config)
@@ -11,6 +14,13 @@ type config struct {
 	headers      map[string]string
 	proxy        string
 	requestLog   bool
+	retry        *fclient.RetryConfig
+	timeout      time.Duration
+	cookieJar    *CookieJar
+
+	propagateDeadline bool
+
+	idempotencyKey bool
 	error
 }
 
@@ -42,3 +52,40 @@ func WithRequestLog() Option {
 		c.requestLog = true
 	}
 }
+
+func WithRetry(maxRetryCount int, initialInterval time.Duration) Option {
+	return func(c *config) {
+		c.retry = &fclient.RetryConfig{
+			InitialInterval: initialInterval,
+			MaxRetryCount:   maxRetryCount,
+		}
+	}
+}
+
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *config) {
+		c.timeout = timeout
+	}
+}
+
+func WithDeadlinePropagation() Option {
+	return func(c *config) {
+		c.propagateDeadline = true
+	}
+}
+
+func WithCookieJar(jar *CookieJar) Option {
+	return func(c *config) {
+		c.cookieJar = jar
+	}
+}
+
+// WithIdempotencyKey sends an Idempotency-Key on POST and PATCH requests. A key
+// generated by the client lasts for one Send and its built-in retries only;
+// callers retrying an operation themselves must pin the key with
+// ContextWithIdempotencyKey, or every attempt looks new to the server.
+func WithIdempotencyKey() Option {
+	return func(c *config) {
+		c.idempotencyKey = true
+	}
+}
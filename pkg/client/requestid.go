@@ -0,0 +1,19 @@
+package client
+
+import (
+	"github.com/gofiber/fiber/v3"
+	fclient "github.com/gofiber/fiber/v3/client"
+	"github.com/nphiro/mesh/pkg/cmlt"
+)
+
+// forwardRequestID passes the inbound request ID on to the callee so both
+// services log the same request_id. A header set explicitly by the caller wins.
+func forwardRequestID(_ *fclient.Client, req *fclient.Request) error {
+	if len(req.Header(fiber.HeaderXRequestID)) > 0 {
+		return nil
+	}
+	if id, ok := cmlt.RequestIDFromContext(req.Context()); ok {
+		req.SetHeader(fiber.HeaderXRequestID, id)
+	}
+	return nil
+}
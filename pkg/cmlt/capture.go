@@ -0,0 +1,17 @@
+package cmlt
+
+import "context"
+
+var skipCaptureContextKey contextKey = "skip_capture"
+
+// ContextWithoutCapture marks ctx so records logged with it at Warn and above
+// are written but not sent to Sentry, e.g. summaries of failures that are
+// reported on their own.
+func ContextWithoutCapture(ctx context.Context) context.Context {
+	return context.WithValue(ctx, skipCaptureContextKey, true)
+}
+
+func captureSkipped(ctx context.Context) bool {
+	skip, _ := ctx.Value(skipCaptureContextKey).(bool)
+	return skip
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"sync"
 
 	sentryotel "github.com/getsentry/sentry-go/otel"
 	"go.opentelemetry.io/otel"
@@ -18,6 +19,9 @@ import (
 var (
 	appTracer     = otel.Tracer("app")
 	shutdownFuncs []shutdownFunc
+
+	flushOnce sync.Once
+	flushErr  error
 )
 
 type shutdownFunc struct {
@@ -74,15 +78,21 @@ func Setup(serviceName string, config *Config) {
 	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(textMapPropagators...))
 }
 
+// Flush delivers buffered telemetry and shuts its exporters down. Only the
+// first call flushes; later calls return its result, so a deferred Flush in
+// main is harmless after server.Run has flushed on shutdown.
 func Flush() error {
-	var errs []error
-	for _, shutdownFunc := range shutdownFuncs {
-		slog.Info(fmt.Sprintf("Flushing buffered %s data", shutdownFunc.name))
-		if err := shutdownFunc.apply(context.Background()); err != nil {
-			errs = append(errs, err)
+	flushOnce.Do(func() {
+		var errs []error
+		for _, shutdownFunc := range shutdownFuncs {
+			slog.Info(fmt.Sprintf("Flushing buffered %s data", shutdownFunc.name))
+			if err := shutdownFunc.apply(context.Background()); err != nil {
+				errs = append(errs, err)
+			}
 		}
-	}
-	return errors.Join(errs...)
+		flushErr = errors.Join(errs...)
+	})
+	return flushErr
 }
 
 func AppTracer() trace.Tracer {
@@ -0,0 +1,18 @@
+package cmlt
+
+import "context"
+
+type contextKey string
+
+var requestIDContextKey contextKey = "request_id"
+
+// ContextWithRequestID tags ctx with the request ID; records logged with ctx
+// carry it as request_id.
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDContextKey, id)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDContextKey).(string)
+	return id, ok && id != ""
+}
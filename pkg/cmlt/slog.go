@@ -18,14 +18,14 @@ type slogHandler struct {
 	handler slog.Handler
 }
 
+var minimumLevel = new(slog.LevelVar)
+
 func init() {
-	var (
-		writer       io.Writer = os.Stdout
-		minimumLevel           = slog.LevelInfo
-	)
+	var writer io.Writer = os.Stdout
+	minimumLevel.Set(slog.LevelInfo)
 	if env.InLocalMachine() {
 		writer = &prettierStdout{}
-		minimumLevel = slog.LevelDebug
+		minimumLevel.Set(slog.LevelDebug)
 	}
 	slog.SetDefault(slog.New(
 		&slogHandler{
@@ -37,6 +37,15 @@ func init() {
 	))
 }
 
+func LogLevel() slog.Level {
+	return minimumLevel.Level()
+}
+
+// SetLogLevel changes the minimum level of the default logger at runtime.
+func SetLogLevel(level slog.Level) {
+	minimumLevel.Set(level)
+}
+
 func handleAttrs(attr slog.Attr) (any, error) {
 	if attr.Value.Kind() == slog.KindGroup {
 		groupMap := map[string]any{}
@@ -64,7 +73,8 @@ func (h *slogHandler) Enabled(ctx context.Context, lvl slog.Level) bool {
 }
 
 func (h *slogHandler) Handle(ctx context.Context, rec slog.Record) error {
-	if rec.Level >= slog.LevelWarn {
+	requestID, hasRequestID := RequestIDFromContext(ctx)
+	if rec.Level >= slog.LevelWarn && !captureSkipped(ctx) {
 		if sentryClient := sentry.CurrentHub().Client(); sentryClient != nil {
 			event := sentryClient.EventFromMessage(rec.Message, sentry.LevelError)
 			if rec.Level == slog.LevelWarn {
@@ -81,6 +91,12 @@ func (h *slogHandler) Handle(ctx context.Context, rec slog.Record) error {
 				event.Extra[attr.Key] = val
 				return true
 			})
+			if hasRequestID {
+				if event.Tags == nil {
+					event.Tags = map[string]string{}
+				}
+				event.Tags["request_id"] = requestID
+			}
 			event.SetException(exception, 10)
 			sentryClient.CaptureEvent(event, &sentry.EventHint{Context: ctx, OriginalException: exception}, nil)
 		}
@@ -90,6 +106,9 @@ func (h *slogHandler) Handle(ctx context.Context, rec slog.Record) error {
 		rec.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()))
 		rec.AddAttrs(slog.String("span_id", spanContext.SpanID().String()))
 	}
+	if hasRequestID {
+		rec.AddAttrs(slog.String("request_id", requestID))
+	}
 	return h.handler.Handle(ctx, rec)
 }
 
@@ -0,0 +1,211 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/nphiro/mesh/pkg/client"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "mesh.pkg/graphql"
+
+var (
+	operationPattern = regexp.MustCompile(`^(query|mutation|subscription)\b\s*([_A-Za-z][_0-9A-Za-z]*)?`)
+	fragmentPattern  = regexp.MustCompile(`^fragment\b`)
+)
+
+type Client struct {
+	client client.Client
+	path   string
+}
+
+func New(c client.Client, path string) *Client {
+	return &Client{
+		client: c,
+		path:   path,
+	}
+}
+
+type request[V any] struct {
+	Query         string `json:"query"`
+	OperationName string `json:"operationName,omitempty"`
+	Variables     V      `json:"variables,omitempty"`
+}
+
+type response[T any] struct {
+	Data   *T     `json:"data"`
+	Errors Errors `json:"errors"`
+}
+
+type Location struct {
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type Error struct {
+	Message    string         `json:"message"`
+	Locations  []Location     `json:"locations,omitempty"`
+	Path       []any          `json:"path,omitempty"`
+	Extensions map[string]any `json:"extensions,omitempty"`
+}
+
+func (e Error) Error() string {
+	if len(e.Path) == 0 {
+		return e.Message
+	}
+	path := make([]string, len(e.Path))
+	for i, p := range e.Path {
+		path[i] = fmt.Sprint(p)
+	}
+	return fmt.Sprintf("%s: %s", strings.Join(path, "."), e.Message)
+}
+
+// Errors is returned when the response carries a GraphQL errors array.
+// Data decoded alongside it is still returned, since results may be partial.
+type Errors []Error
+
+func (e Errors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return "graphql: " + strings.Join(messages, "; ")
+}
+
+type StatusError struct {
+	StatusCode int
+	Body       []byte
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("graphql: unexpected status %d", e.StatusCode)
+}
+
+// Do executes a query or mutation and decodes its data into T.
+// The operation type and name are taken from the document for span naming.
+func Do[T any, V any](ctx context.Context, c *Client, query string, variables V) (*T, error) {
+	operationType, operationName := operation(query)
+	spanName := operationType
+	if operationName != "" {
+		spanName += " " + operationName
+	}
+	ctx, span := otel.Tracer(tracerName).Start(ctx, spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("graphql.operation.type", operationType),
+			attribute.String("graphql.operation.name", operationName),
+			attribute.String("graphql.document", query),
+		),
+	)
+	defer span.End()
+
+	data, err := do[T](ctx, c, request[V]{
+		Query:         query,
+		OperationName: operationName,
+		Variables:     variables,
+	})
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return data, err
+}
+
+// operation returns the type and name of the first operation in the document,
+// past comments and fragment definitions. Shorthand queries have no name.
+func operation(document string) (string, string) {
+	for {
+		document = skipIgnored(document)
+		if !fragmentPattern.MatchString(document) {
+			break
+		}
+		document = skipDefinition(document)
+	}
+	if m := operationPattern.FindStringSubmatch(document); m != nil {
+		return m[1], m[2]
+	}
+	return "query", ""
+}
+
+// skipIgnored drops leading whitespace, commas and comments.
+func skipIgnored(s string) string {
+	for {
+		s = strings.TrimLeft(s, " \t\r\n,\ufeff")
+		if !strings.HasPrefix(s, "#") {
+			return s
+		}
+		if i := strings.IndexAny(s, "\r\n"); i >= 0 {
+			s = s[i:]
+		} else {
+			return ""
+		}
+	}
+}
+
+// skipDefinition drops everything up to the brace closing the definition's
+// selection set, ignoring braces in strings and comments.
+func skipDefinition(s string) string {
+	depth := 0
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '{':
+			depth++
+		case '}':
+			if depth--; depth == 0 {
+				return s[i+1:]
+			}
+		case '#':
+			end := strings.IndexAny(s[i:], "\r\n")
+			if end < 0 {
+				return ""
+			}
+			i += end
+		case '"':
+			if strings.HasPrefix(s[i:], `"""`) {
+				end := strings.Index(s[i+3:], `"""`)
+				if end < 0 {
+					return ""
+				}
+				i += end + 5
+				continue
+			}
+			for i++; i < len(s) && s[i] != '"'; i++ {
+				if s[i] == '\\' {
+					i++
+				}
+			}
+		}
+	}
+	return ""
+}
+
+func do[T any, V any](ctx context.Context, c *Client, req request[V]) (*T, error) {
+	res, err := c.client.Post(client.ContextWithHeaders(ctx, map[string]string{
+		"Content-Type": "application/json",
+		"Accept":       "application/graphql-response+json, application/json",
+	}), c.path, req)
+	if err != nil {
+		return nil, err
+	}
+	var body response[T]
+	if err := json.Unmarshal(res.Body, &body); err != nil {
+		if res.StatusCode >= 300 {
+			return nil, &StatusError{StatusCode: res.StatusCode, Body: res.Body}
+		}
+		return nil, err
+	}
+	if len(body.Errors) > 0 {
+		return body.Data, body.Errors
+	}
+	if res.StatusCode >= 300 {
+		return nil, &StatusError{StatusCode: res.StatusCode, Body: res.Body}
+	}
+	return body.Data, nil
+}
@@ -0,0 +1,141 @@
+package graphql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/nphiro/mesh/pkg/client"
+)
+
+type order struct {
+	ID       string  `json:"id"`
+	Customer *string `json:"customer"`
+}
+
+type orderData struct {
+	Order *order `json:"order"`
+}
+
+// newTestClient serves every request with status and body, recording the
+// last request body.
+func newTestClient(t *testing.T, status int, body string) (*Client, *request[map[string]any]) {
+	t.Helper()
+	var last request[map[string]any]
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&last); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Header().Set("Content-Type", "application/graphql-response+json")
+		w.WriteHeader(status)
+		_, _ = w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	c, err := client.New(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return New(c, "/graphql"), &last
+}
+
+func TestDoPartialData(t *testing.T) {
+	c, last := newTestClient(t, http.StatusOK, `{
+		"data": {"order": {"id": "o-1", "customer": null}},
+		"errors": [{"message": "customer service unavailable", "path": ["order", "customer"], "locations": [{"line": 1, "column": 30}]}]
+	}`)
+	data, err := Do[orderData](context.Background(), c, `query GetOrder($id: ID!) { order(id: $id) { id customer } }`, map[string]any{"id": "o-1"})
+
+	var gqlErrs Errors
+	if !errors.As(err, &gqlErrs) || len(gqlErrs) != 1 {
+		t.Fatalf("error = %v, want one GraphQL error", err)
+	}
+	if got := err.Error(); got != "graphql: order.customer: customer service unavailable" {
+		t.Errorf("error message = %q", got)
+	}
+	if gqlErrs[0].Locations[0] != (Location{Line: 1, Column: 30}) {
+		t.Errorf("locations = %v", gqlErrs[0].Locations)
+	}
+	if data == nil || data.Order == nil || data.Order.ID != "o-1" || data.Order.Customer != nil {
+		t.Errorf("data = %+v, want the partial order", data)
+	}
+	if last.OperationName != "GetOrder" || last.Variables["id"] != "o-1" {
+		t.Errorf("request = %+v", last)
+	}
+}
+
+func TestDoStatus(t *testing.T) {
+	tests := []struct {
+		name       string
+		status     int
+		body       string
+		wantStatus int
+		wantErrors bool
+	}{
+		{"non-JSON error page", http.StatusBadGateway, `<html>bad gateway</html>`, http.StatusBadGateway, false},
+		{"JSON without errors", http.StatusInternalServerError, `{"data": null}`, http.StatusInternalServerError, false},
+		{"request errors", http.StatusBadRequest, `{"errors": [{"message": "unknown field"}]}`, 0, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := newTestClient(t, tt.status, tt.body)
+			data, err := Do[orderData](context.Background(), c, `{ order(id: "o-1") { id } }`, map[string]any(nil))
+			if data != nil {
+				t.Errorf("data = %+v, want nil", data)
+			}
+			var statusErr *StatusError
+			if got := errors.As(err, &statusErr); got != (tt.wantStatus != 0) {
+				t.Fatalf("error = %v, want StatusError %t", err, tt.wantStatus != 0)
+			}
+			if statusErr != nil && (statusErr.StatusCode != tt.wantStatus || string(statusErr.Body) != tt.body) {
+				t.Errorf("StatusError = %d %q", statusErr.StatusCode, statusErr.Body)
+			}
+			var gqlErrs Errors
+			if got := errors.As(err, &gqlErrs); got != tt.wantErrors {
+				t.Errorf("error = %v, want Errors %t", err, tt.wantErrors)
+			}
+		})
+	}
+}
+
+func TestOperation(t *testing.T) {
+	tests := []struct {
+		name     string
+		document string
+		wantType string
+		wantName string
+	}{
+		{"named query", `query GetOrder { order { id } }`, "query", "GetOrder"},
+		{"mutation", "mutation CancelOrder($id: ID!) { cancel(id: $id) }", "mutation", "CancelOrder"},
+		{"anonymous", `subscription { orderUpdated { id } }`, "subscription", ""},
+		{"shorthand", `{ order { id } }`, "query", ""},
+		{"leading comments", "# Fetches an order.\n  # Used by checkout.\nquery GetOrder { order { id } }", "query", "GetOrder"},
+		{
+			name: "leading fragments",
+			document: `fragment OrderFields on Order { id lines { sku } }
+				fragment More on Order { total }
+				mutation PlaceOrder { place { ...OrderFields ...More } }`,
+			wantType: "mutation",
+			wantName: "PlaceOrder",
+		},
+		{
+			name: "braces in fragment strings and comments",
+			document: `fragment F on Order { a(x: "}") # }
+				b(y: """ } """) }
+				query Q { ...F }`,
+			wantType: "query",
+			wantName: "Q",
+		},
+		{"comment only", "# nothing here", "query", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotType, gotName := operation(tt.document)
+			if gotType != tt.wantType || gotName != tt.wantName {
+				t.Errorf("operation = %q %q, want %q %q", gotType, gotName, tt.wantType, tt.wantName)
+			}
+		})
+	}
+}
@@ -0,0 +1,124 @@
+package server
+
+import (
+	"log/slog"
+	"math/rand/v2"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/cmlt"
+)
+
+const redacted = "REDACTED"
+
+type AccessLogConfig struct {
+	// SkipPaths are request paths that are never logged, e.g. probes.
+	SkipPaths []string
+	// SuccessSampleRate is the fraction of 2xx responses logged, between 0 and 1.
+	// Zero logs every response.
+	SuccessSampleRate float64
+	// SkipSuccess drops every 2xx response, leaving only redirects and failures.
+	SkipSuccess bool
+	// RequestHeaders logs the request headers.
+	RequestHeaders bool
+	// RedactHeaders and RedactQuery name values replaced before logging;
+	// they extend the defaults rather than replacing them.
+	RedactHeaders []string
+	RedactQuery   []string
+}
+
+var (
+	defaultRedactHeaders = []string{fiber.HeaderAuthorization, fiber.HeaderProxyAuthorization, fiber.HeaderCookie, "X-Api-Key"}
+	defaultRedactQuery   = []string{"access_token", "api_key", "password", "token"}
+)
+
+func accessLogMiddleware(cfg AccessLogConfig) fiber.Handler {
+	redactHeaders := map[string]bool{}
+	for _, h := range append(slices.Clone(defaultRedactHeaders), cfg.RedactHeaders...) {
+		redactHeaders[strings.ToLower(h)] = true
+	}
+	redactQuery := map[string]bool{}
+	for _, q := range append(slices.Clone(defaultRedactQuery), cfg.RedactQuery...) {
+		redactQuery[strings.ToLower(q)] = true
+	}
+
+	return func(c fiber.Ctx) error {
+		if slices.Contains(cfg.SkipPaths, c.Path()) {
+			return c.Next()
+		}
+		start := time.Now()
+		err := next(c)
+		latency := time.Since(start)
+
+		status := c.Response().StatusCode()
+		if status < fiber.StatusMultipleChoices && (cfg.SkipSuccess || cfg.SuccessSampleRate > 0 && rand.Float64() >= cfg.SuccessSampleRate) {
+			return err
+		}
+		level := slog.LevelInfo
+		switch {
+		case status >= fiber.StatusInternalServerError:
+			level = slog.LevelError
+		case status >= fiber.StatusBadRequest:
+			level = slog.LevelWarn
+		}
+
+		// Copies are taken because slog handlers may keep attributes past the request.
+		attrs := []slog.Attr{
+			slog.String("request_method", strings.Clone(c.Method())),
+			slog.String("request_route", routeLabel(c)),
+			slog.String("request_path", strings.Clone(c.Path())),
+			slog.String("request_query", redactedQuery(string(c.Request().URI().QueryString()), redactQuery)),
+			slog.Int("request_bytes", len(c.Request().Body())),
+			slog.Int("response_status", status),
+			slog.Int("response_bytes", len(c.Response().Body())),
+			slog.Int64("latency_ms", latency.Milliseconds()),
+			slog.String("client_ip", ClientIP(c)),
+			slog.String("user_agent", strings.Clone(c.Get(fiber.HeaderUserAgent))),
+		}
+		if identity, ok := PeerIdentityFromContext(c.Context()); ok {
+			attrs = append(attrs, slog.String("client_subject", identity.Subject))
+			if identity.SPIFFEID != "" {
+				attrs = append(attrs, slog.String("client_spiffe_id", identity.SPIFFEID))
+			}
+		}
+		if cfg.RequestHeaders {
+			headers := map[string]string{}
+			for key, values := range c.GetReqHeaders() {
+				value := strings.Join(values, ", ")
+				if redactHeaders[strings.ToLower(key)] {
+					value = redacted
+				}
+				headers[strings.Clone(key)] = value
+			}
+			attrs = append(attrs, slog.Any("request_headers", headers))
+		}
+		if handlerErr := handlerError(c); handlerErr != nil {
+			attrs = append(attrs, slog.Any("error", handlerErr))
+		}
+		// The level grades the entry, but failures already reach Sentry through
+		// errorHandler and recoverMiddleware, and client errors are not faults.
+		slog.LogAttrs(cmlt.ContextWithoutCapture(c.Context()), level, "Request completed", attrs...)
+		return err
+	}
+}
+
+func redactedQuery(rawQuery string, redact map[string]bool) string {
+	if rawQuery == "" {
+		return ""
+	}
+	query, err := url.ParseQuery(rawQuery)
+	if err != nil {
+		return redacted
+	}
+	for key, values := range query {
+		if redact[strings.ToLower(key)] {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	return query.Encode()
+}
@@ -0,0 +1,95 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+type ACMEConfig struct {
+	// Hosts are the only server names certificates are requested for.
+	Hosts []string
+	// DirectoryURL defaults to the Let's Encrypt production directory.
+	DirectoryURL string
+	Email        string
+	// CacheDir keeps the account key and certificates across restarts. Without
+	// it every start orders new certificates and soon hits CA rate limits.
+	CacheDir string
+	// HTTPChallengePort, typically "80", answers HTTP-01 challenges and redirects
+	// other plain HTTP requests to HTTPS. TLS-ALPN-01 is always answered on the
+	// main port.
+	HTTPChallengePort string
+	// HTTPClient talks to the ACME server, e.g. one trusting a Pebble test CA.
+	HTTPClient *http.Client
+}
+
+func newACMEManager(cfg ACMEConfig) (*autocert.Manager, error) {
+	if len(cfg.Hosts) == 0 {
+		return nil, errors.New("server: acme needs at least one host")
+	}
+	manager := &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(cfg.Hosts...),
+		Email:      cfg.Email,
+		Client: &acme.Client{
+			DirectoryURL: cfg.DirectoryURL,
+			HTTPClient:   cfg.HTTPClient,
+		},
+	}
+	if cfg.CacheDir != "" {
+		manager.Cache = autocert.DirCache(cfg.CacheDir)
+	}
+	return manager, nil
+}
+
+type acmeChallengeServer struct {
+	addr   string
+	server *http.Server
+}
+
+func newACMEChallengeServer(port string, manager *autocert.Manager) *acmeChallengeServer {
+	challenge := manager.HTTPHandler(nil)
+	return &acmeChallengeServer{
+		addr: ":" + strings.TrimPrefix(port, ":"),
+		server: &http.Server{
+			// autocert checks the Host header against the whitelist as is, so a
+			// port from a non-standard challenge port would never match.
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if host, _, err := net.SplitHostPort(r.Host); err == nil {
+					r.Host = host
+				}
+				challenge.ServeHTTP(w, r)
+			}),
+			ReadHeaderTimeout: 10 * time.Second,
+		},
+	}
+}
+
+// start binds before returning so a port conflict fails Run immediately.
+func (s *acmeChallengeServer) start(ctx context.Context) error {
+	ln, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+	go func() {
+		if err := s.server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+			slog.ErrorContext(ctx, "ACME challenge server error", slog.Any("error", err))
+		}
+	}()
+	return nil
+}
+
+func (s *acmeChallengeServer) shutdown(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), 5*time.Second)
+	defer cancel()
+	if err := s.server.Shutdown(ctx); err != nil {
+		slog.ErrorContext(ctx, "Shutdown ACME challenge server error", slog.Any("error", err))
+	}
+}
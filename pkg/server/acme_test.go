@@ -0,0 +1,305 @@
+package server
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+const testACMEHost = "example.test"
+
+// testACME is a minimal RFC 8555 CA in the spirit of Pebble. It validates
+// HTTP-01 challenges through the challenge server's handler, as if it had
+// dialled port 80, and issues certificates from a throwaway root.
+type testACME struct {
+	*httptest.Server
+	t         *testing.T
+	challenge http.Handler
+	root      *x509.Certificate
+	rootKey   crypto.Signer
+
+	mu         sync.Mutex
+	orders     int
+	authzValid bool
+	chain      []byte
+}
+
+func newTestACME(t *testing.T) *testACME {
+	t.Helper()
+	rootKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test acme root"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &rootKey.PublicKey, rootKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testACME{t: t, root: root, rootKey: rootKey}
+	s.Server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testACME) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Replay-Nonce", base64.RawURLEncoding.EncodeToString([]byte(time.Now().String())))
+	payload := s.payload(r)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch r.URL.Path {
+	case "/dir":
+		s.json(w, http.StatusOK, map[string]string{
+			"newNonce":   s.URL + "/nonce",
+			"newAccount": s.URL + "/account",
+			"newOrder":   s.URL + "/order",
+		})
+	case "/nonce":
+		w.WriteHeader(http.StatusOK)
+	case "/account":
+		w.Header().Set("Location", s.URL+"/account/1")
+		s.json(w, http.StatusCreated, map[string]string{"status": "valid"})
+	case "/order":
+		s.orders++
+		s.authzValid = false
+		s.chain = nil
+		w.Header().Set("Location", s.URL+"/order/1")
+		s.json(w, http.StatusCreated, s.order())
+	case "/order/1":
+		s.json(w, http.StatusOK, s.order())
+	case "/authz/1":
+		s.json(w, http.StatusOK, s.authz())
+	case "/chal/1":
+		s.validate()
+		s.json(w, http.StatusOK, s.authz()["challenges"].([]any)[0])
+	case "/finalize/1":
+		var req struct {
+			CSR string `json:"csr"`
+		}
+		if err := json.Unmarshal(payload, &req); err != nil {
+			s.t.Errorf("finalize payload: %v", err)
+		}
+		s.issue(req.CSR)
+		s.json(w, http.StatusOK, s.order())
+	case "/cert/1":
+		w.Header().Set("Content-Type", "application/pem-certificate-chain")
+		_, _ = w.Write(s.chain)
+	default:
+		s.t.Errorf("unexpected ACME request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+// payload decodes the JWS payload; signatures are not checked.
+func (s *testACME) payload(r *http.Request) []byte {
+	if r.Method != http.MethodPost {
+		return nil
+	}
+	var jws struct {
+		Payload string `json:"payload"`
+	}
+	body, _ := io.ReadAll(r.Body)
+	if err := json.Unmarshal(body, &jws); err != nil {
+		s.t.Errorf("%s: decode jws: %v", r.URL.Path, err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(jws.Payload)
+	return payload
+}
+
+func (s *testACME) json(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func (s *testACME) order() map[string]any {
+	order := map[string]any{
+		"status":         "pending",
+		"identifiers":    []any{map[string]string{"type": "dns", "value": testACMEHost}},
+		"authorizations": []string{s.URL + "/authz/1"},
+		"finalize":       s.URL + "/finalize/1",
+	}
+	switch {
+	case s.chain != nil:
+		order["status"] = "valid"
+		order["certificate"] = s.URL + "/cert/1"
+	case s.authzValid:
+		order["status"] = "ready"
+	}
+	return order
+}
+
+func (s *testACME) authz() map[string]any {
+	status := "pending"
+	if s.authzValid {
+		status = "valid"
+	}
+	return map[string]any{
+		"status":     status,
+		"identifier": map[string]string{"type": "dns", "value": testACMEHost},
+		"challenges": []any{map[string]string{
+			"type":   "http-01",
+			"url":    s.URL + "/chal/1",
+			"token":  "token-1",
+			"status": status,
+		}},
+	}
+}
+
+// validate fetches the key authorization the way a CA would, with the port
+// a non-standard challenge listener leaves in the Host header.
+func (s *testACME) validate() {
+	req := httptest.NewRequest(http.MethodGet, "http://"+testACMEHost+":5002/.well-known/acme-challenge/token-1", nil)
+	rec := httptest.NewRecorder()
+	s.challenge.ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "token-1.") {
+		s.t.Errorf("challenge response = %d %q, want key authorization", rec.Code, rec.Body.String())
+		return
+	}
+	s.authzValid = true
+}
+
+func (s *testACME) issue(csrB64 string) {
+	der, err := base64.RawURLEncoding.DecodeString(csrB64)
+	if err != nil {
+		s.t.Errorf("decode csr: %v", err)
+		return
+	}
+	csr, err := x509.ParseCertificateRequest(der)
+	if err != nil {
+		s.t.Errorf("parse csr: %v", err)
+		return
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(int64(s.orders + 1)),
+		Subject:      csr.Subject,
+		DNSNames:     csr.DNSNames,
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(90 * 24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	leaf, err := x509.CreateCertificate(rand.Reader, template, s.root, csr.PublicKey, s.rootKey)
+	if err != nil {
+		s.t.Errorf("issue certificate: %v", err)
+		return
+	}
+	s.chain = append(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: leaf}),
+		pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: s.root.Raw})...)
+}
+
+func newTestACMEChallenge(t *testing.T) (*testACME, *acmeChallengeServer, func(name string) (*tls.Certificate, error)) {
+	t.Helper()
+	ca := newTestACME(t)
+	manager, err := newACMEManager(ACMEConfig{
+		Hosts:        []string{testACMEHost},
+		DirectoryURL: ca.URL + "/dir",
+		Email:        "ops@example.test",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	challenge := newACMEChallengeServer("5002", manager)
+	ca.challenge = challenge.server.Handler
+	getCertificate := func(name string) (*tls.Certificate, error) {
+		return manager.GetCertificate(&tls.ClientHelloInfo{ServerName: name})
+	}
+	return ca, challenge, getCertificate
+}
+
+func TestACMEIssuesCertificateOverHTTP01(t *testing.T) {
+	ca, _, getCertificate := newTestACMEChallenge(t)
+
+	cert, err := getCertificate(testACMEHost)
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	if err := cert.Leaf.VerifyHostname(testACMEHost); err != nil {
+		t.Errorf("leaf: %v", err)
+	}
+	if len(cert.Certificate) != 2 {
+		t.Errorf("chain length = %d, want 2", len(cert.Certificate))
+	}
+
+	// Later handshakes are served from memory without a new order.
+	if _, err := getCertificate(testACMEHost); err != nil {
+		t.Fatalf("GetCertificate again: %v", err)
+	}
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if ca.orders != 1 {
+		t.Errorf("orders = %d, want 1", ca.orders)
+	}
+}
+
+func TestACMERejectsUnlistedHosts(t *testing.T) {
+	ca, _, getCertificate := newTestACMEChallenge(t)
+
+	if _, err := getCertificate("other.test"); err == nil {
+		t.Fatal("GetCertificate succeeded for a host not in Hosts")
+	}
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if ca.orders != 0 {
+		t.Errorf("orders = %d, want 0", ca.orders)
+	}
+}
+
+func TestACMEChallengeServer(t *testing.T) {
+	_, challenge, _ := newTestACMEChallenge(t)
+
+	tests := []struct {
+		name     string
+		url      string
+		want     int
+		location string
+	}{
+		{"redirects to https", "http://" + testACMEHost + ":5002/orders?id=1", http.StatusFound, "https://" + testACMEHost + "/orders?id=1"},
+		{"unknown token", "http://" + testACMEHost + ":5002/.well-known/acme-challenge/nope", http.StatusNotFound, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			challenge.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, tt.url, nil))
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d", rec.Code, tt.want)
+			}
+			if got := rec.Header().Get("Location"); got != tt.location {
+				t.Errorf("Location = %q, want %q", got, tt.location)
+			}
+		})
+	}
+}
+
+func TestNewACMEManagerNeedsHosts(t *testing.T) {
+	if _, err := newACMEManager(ACMEConfig{}); err == nil {
+		t.Fatal("newACMEManager succeeded without hosts")
+	}
+}
@@ -0,0 +1,76 @@
+package server
+
+import (
+	"log/slog"
+	"runtime/debug"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/pprof"
+	"github.com/nphiro/mesh/pkg/cmlt"
+)
+
+type AdminConfig struct {
+	Port string
+	// LoopbackOnly binds the admin listener to 127.0.0.1, e.g. for sidecar scraping.
+	LoopbackOnly bool
+}
+
+type buildInfo struct {
+	GoVersion string            `json:"go_version"`
+	Path      string            `json:"path"`
+	Version   string            `json:"version"`
+	Settings  map[string]string `json:"settings,omitempty"`
+}
+
+func newAdminApp() *fiber.App {
+	app := fiber.New(fiber.Config{
+		ErrorHandler: errorHandler,
+	})
+	app.Use(pprof.New())
+	app.Get("/buildinfo", buildInfoHandler)
+	app.Get("/loglevel", logLevelHandler)
+	app.Put("/loglevel", setLogLevelHandler)
+	return app
+}
+
+func (cfg AdminConfig) address() string {
+	port := ":" + strings.TrimPrefix(cfg.Port, ":")
+	if cfg.LoopbackOnly {
+		return "127.0.0.1" + port
+	}
+	return port
+}
+
+func buildInfoHandler(c fiber.Ctx) error {
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return fiber.NewError(fiber.StatusNotFound, "build info is not available")
+	}
+	res := buildInfo{
+		GoVersion: info.GoVersion,
+		Path:      info.Main.Path,
+		Version:   info.Main.Version,
+		Settings:  map[string]string{},
+	}
+	for _, setting := range info.Settings {
+		if strings.HasPrefix(setting.Key, "vcs") || setting.Key == "GOOS" || setting.Key == "GOARCH" {
+			res.Settings[setting.Key] = setting.Value
+		}
+	}
+	return c.JSON(res)
+}
+
+func logLevelHandler(c fiber.Ctx) error {
+	return c.SendString(cmlt.LogLevel().String())
+}
+
+func setLogLevelHandler(c fiber.Ctx) error {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(string(c.Body())))); err != nil {
+		return NewProblem(fiber.StatusBadRequest, err.Error())
+	}
+	cmlt.SetLogLevel(level)
+	slog.InfoContext(c.Context(), "Changed log level", slog.String("level", level.String()))
+	return c.SendString(level.String())
+}
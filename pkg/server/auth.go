@@ -0,0 +1,244 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type AuthConfig struct {
+	// JWKSURL is the identity provider's key set. It is refreshed every
+	// RefreshInterval while the server runs and whenever a token names an
+	// unknown key ID.
+	JWKSURL string
+	// Keys are static verification keys by key ID, checked before the JWKS.
+	// HMAC secrets are given as []byte and need an HS algorithm in Algorithms.
+	Keys map[string]crypto.PublicKey
+	// Issuer and Audience must match the token; a token naming any one of
+	// the audiences is accepted.
+	Issuer   string
+	Audience []string
+	// ClockSkew tolerated on exp, nbf and iat. Defaults to 1 minute.
+	ClockSkew time.Duration
+	// RefreshInterval defaults to 15 minutes.
+	RefreshInterval time.Duration
+	// Algorithms defaults to the asymmetric RS, PS, ES and EdDSA algorithms.
+	Algorithms []string
+	// SkipPaths are served without a token, e.g. public webhooks.
+	SkipPaths []string
+	// HTTPClient fetches the JWKS.
+	HTTPClient *http.Client
+}
+
+var defaultAuthAlgorithms = []string{"RS256", "RS384", "RS512", "PS256", "PS384", "PS512", "ES256", "ES384", "ES512", "EdDSA"}
+
+// Claims are the verified claims of the request's bearer token.
+// Scopes are read from "scope" or "scp", roles from "roles".
+type Claims struct {
+	jwt.RegisteredClaims
+	Scopes []string
+	Roles  []string
+
+	raw json.RawMessage
+}
+
+func (c *Claims) UnmarshalJSON(b []byte) error {
+	var claims struct {
+		jwt.RegisteredClaims
+		Scope string          `json:"scope"`
+		Scp   json.RawMessage `json:"scp"`
+		Roles json.RawMessage `json:"roles"`
+	}
+	if err := json.Unmarshal(b, &claims); err != nil {
+		return err
+	}
+	c.RegisteredClaims = claims.RegisteredClaims
+	c.Scopes = strings.Fields(claims.Scope)
+	if scp := stringOrList(claims.Scp); len(scp) > 0 {
+		c.Scopes = scp
+	}
+	c.Roles = stringOrList(claims.Roles)
+	c.raw = slices.Clone(b)
+	return nil
+}
+
+// Decode unmarshals the full claim set into v, for provider-specific claims.
+func (c *Claims) Decode(v any) error {
+	return json.Unmarshal(c.raw, v)
+}
+
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+func (c *Claims) HasRole(role string) bool {
+	return slices.Contains(c.Roles, role)
+}
+
+// stringOrList accepts both a space-separated string and a JSON array.
+func stringOrList(raw json.RawMessage) []string {
+	var list []string
+	if err := json.Unmarshal(raw, &list); err == nil {
+		return list
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return strings.Fields(s)
+	}
+	return nil
+}
+
+type claimsContextKey struct{}
+
+// ContextWithClaims is used by the auth middleware; tests may call it to
+// run handlers as a given principal without minting tokens.
+func ContextWithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
+
+type authenticator struct {
+	cfg    AuthConfig
+	parser *jwt.Parser
+	jwks   *jwks
+}
+
+func newAuthenticator(cfg AuthConfig) (*authenticator, error) {
+	if cfg.JWKSURL == "" && len(cfg.Keys) == 0 {
+		return nil, errors.New("server: auth needs a JWKS URL or static keys")
+	}
+	if cfg.Issuer == "" || len(cfg.Audience) == 0 {
+		return nil, errors.New("server: auth needs an issuer and audience")
+	}
+	if cfg.ClockSkew == 0 {
+		cfg.ClockSkew = time.Minute
+	}
+	if cfg.RefreshInterval == 0 {
+		cfg.RefreshInterval = 15 * time.Minute
+	}
+	if len(cfg.Algorithms) == 0 {
+		cfg.Algorithms = defaultAuthAlgorithms
+	}
+	a := &authenticator{
+		cfg: cfg,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods(cfg.Algorithms),
+			jwt.WithIssuer(cfg.Issuer),
+			jwt.WithAudience(cfg.Audience...),
+			jwt.WithLeeway(cfg.ClockSkew),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
+	}
+	if cfg.JWKSURL != "" {
+		a.jwks = newJWKS(cfg.JWKSURL, cfg.HTTPClient, cfg.RefreshInterval)
+	}
+	return a, nil
+}
+
+func (a *authenticator) middleware(c fiber.Ctx) error {
+	if slices.Contains(a.cfg.SkipPaths, c.Path()) {
+		return c.Next()
+	}
+	token, ok := bearerToken(c.Get(fiber.HeaderAuthorization))
+	if !ok {
+		c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+		return NewProblem(fiber.StatusUnauthorized, "missing bearer token")
+	}
+	ctx := c.Context()
+	claims := &Claims{}
+	if _, err := a.parser.ParseWithClaims(token, claims, a.keyfunc(ctx)); err != nil {
+		c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+		return NewProblem(fiber.StatusUnauthorized, err.Error())
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("enduser.id", claims.Subject))
+	c.SetContext(ContextWithClaims(ctx, claims))
+	return c.Next()
+}
+
+func (a *authenticator) keyfunc(ctx context.Context) jwt.Keyfunc {
+	return func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		if key, ok := a.cfg.Keys[kid]; ok {
+			return key, nil
+		}
+		if a.jwks != nil {
+			return a.jwks.key(ctx, kid)
+		}
+		if kid == "" {
+			set := jwt.VerificationKeySet{}
+			for _, key := range a.cfg.Keys {
+				set.Keys = append(set.Keys, key)
+			}
+			return set, nil
+		}
+		return nil, errors.New("unknown signing key " + kid)
+	}
+}
+
+func bearerToken(header string) (string, bool) {
+	scheme, token, ok := strings.Cut(header, " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// RequireScopes rejects requests whose token lacks any of the scopes.
+// It is route middleware: app.Get("/orders", handler, server.RequireScopes("orders:read")).
+func RequireScopes(scopes ...string) fiber.Handler {
+	doc := documentSecurity(append([]string{}, scopes...))
+	handler := func(c fiber.Ctx) error {
+		if describe(c, doc) {
+			return nil
+		}
+		claims, ok := ClaimsFromContext(c.Context())
+		if !ok {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return NewProblem(fiber.StatusUnauthorized, "missing bearer token")
+		}
+		for _, scope := range scopes {
+			if !claims.HasScope(scope) {
+				c.Set(fiber.HeaderWWWAuthenticate, `Bearer error="insufficient_scope", scope="`+strings.Join(scopes, " ")+`"`)
+				return NewProblem(fiber.StatusForbidden, "missing scope "+scope)
+			}
+		}
+		return c.Next()
+	}
+	return markDocumented(handler)
+}
+
+// RequireRole rejects requests whose token has none of the roles.
+func RequireRole(roles ...string) fiber.Handler {
+	doc := documentSecurity([]string{})
+	handler := func(c fiber.Ctx) error {
+		if describe(c, doc) {
+			return nil
+		}
+		claims, ok := ClaimsFromContext(c.Context())
+		if !ok {
+			c.Set(fiber.HeaderWWWAuthenticate, "Bearer")
+			return NewProblem(fiber.StatusUnauthorized, "missing bearer token")
+		}
+		if !slices.ContainsFunc(roles, claims.HasRole) {
+			return NewProblem(fiber.StatusForbidden, "requires role "+strings.Join(roles, " or "))
+		}
+		return c.Next()
+	}
+	return markDocumented(handler)
+}
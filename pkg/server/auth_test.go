@@ -0,0 +1,451 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	testIssuer   = "https://issuer.example"
+	testAudience = "orders"
+)
+
+// testKeySet serves a JWKS whose keys can be swapped, counting fetches.
+type testKeySet struct {
+	*httptest.Server
+	keys    atomic.Pointer[[]jwk]
+	fetches atomic.Int32
+}
+
+func newTestKeySet(t *testing.T, keys ...jwk) *testKeySet {
+	t.Helper()
+	s := &testKeySet{}
+	s.set(keys...)
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.fetches.Add(1)
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": *s.keys.Load()})
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *testKeySet) set(keys ...jwk) {
+	s.keys.Store(&keys)
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) jwk {
+	return jwk{
+		Kty: "RSA",
+		Kid: kid,
+		Use: "sig",
+		N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+		E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) jwk {
+	size := (key.Curve.Params().BitSize + 7) / 8
+	return jwk{
+		Kty: "EC",
+		Kid: kid,
+		Crv: key.Curve.Params().Name,
+		X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, size))),
+		Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, size))),
+	}
+}
+
+func okpJWK(kid string, key ed25519.PublicKey) jwk {
+	return jwk{Kty: "OKP", Kid: kid, Crv: "Ed25519", X: base64.RawURLEncoding.EncodeToString(key)}
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func newECKey(t *testing.T, curve elliptic.Curve) *ecdsa.PrivateKey {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(curve, rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": testIssuer,
+		"aud": testAudience,
+		"sub": "user-1",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func signToken(t *testing.T, method jwt.SigningMethod, key crypto.PrivateKey, kid string, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+func newTestAuthenticator(t *testing.T, jwksURL string) *authenticator {
+	t.Helper()
+	a, err := newAuthenticator(AuthConfig{
+		JWKSURL:  jwksURL,
+		Issuer:   testIssuer,
+		Audience: []string{testAudience},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func (a *authenticator) verify(token string) error {
+	_, err := a.parser.ParseWithClaims(token, &Claims{}, a.keyfunc(context.Background()))
+	return err
+}
+
+func TestAuthenticatorKeyTypes(t *testing.T) {
+	rsaKey := newRSAKey(t)
+	p256 := newECKey(t, elliptic.P256())
+	p384 := newECKey(t, elliptic.P384())
+	p521 := newECKey(t, elliptic.P521())
+	edPublic, edPrivate, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keySet := newTestKeySet(t,
+		rsaJWK("rsa", &rsaKey.PublicKey),
+		ecJWK("p256", &p256.PublicKey),
+		ecJWK("p384", &p384.PublicKey),
+		ecJWK("p521", &p521.PublicKey),
+		okpJWK("ed25519", edPublic),
+	)
+
+	tests := []struct {
+		name   string
+		method jwt.SigningMethod
+		key    crypto.PrivateKey
+		kid    string
+	}{
+		{"RS256", jwt.SigningMethodRS256, rsaKey, "rsa"},
+		{"PS256", jwt.SigningMethodPS256, rsaKey, "rsa"},
+		{"ES256", jwt.SigningMethodES256, p256, "p256"},
+		{"ES384", jwt.SigningMethodES384, p384, "p384"},
+		{"ES512", jwt.SigningMethodES512, p521, "p521"},
+		{"EdDSA", jwt.SigningMethodEdDSA, edPrivate, "ed25519"},
+		{"no kid", jwt.SigningMethodES256, p256, ""},
+	}
+	a := newTestAuthenticator(t, keySet.URL)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.verify(signToken(t, tt.method, tt.key, tt.kid, validClaims())); err != nil {
+				t.Fatalf("verify: %v", err)
+			}
+		})
+	}
+	if n := keySet.fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+}
+
+func TestJWKPublicKeyRejectsInvalidKeys(t *testing.T) {
+	p256 := ecJWK("p256", &newECKey(t, elliptic.P256()).PublicKey)
+	offCurve := p256
+	offCurve.Y = base64.RawURLEncoding.EncodeToString(make([]byte, 32))
+	shortX := p256
+	shortX.X = base64.RawURLEncoding.EncodeToString([]byte{1})
+
+	tests := []struct {
+		name string
+		key  jwk
+	}{
+		{"ec point off curve", offCurve},
+		{"ec point size", shortX},
+		{"ec curve", jwk{Kty: "EC", Crv: "P-192"}},
+		{"okp curve", jwk{Kty: "OKP", Crv: "X25519", X: p256.X}},
+		{"okp size", jwk{Kty: "OKP", Crv: "Ed25519", X: p256.X + "AA"}},
+		{"rsa exponent", jwk{Kty: "RSA", N: "AQAB", E: base64.RawURLEncoding.EncodeToString(big.NewInt(1 << 40).Bytes())}},
+		{"key type", jwk{Kty: "oct"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.key.publicKey(); err == nil {
+				t.Fatal("publicKey succeeded, want error")
+			}
+		})
+	}
+}
+
+func TestJWKSRotation(t *testing.T) {
+	oldKey, newKey := newECKey(t, elliptic.P256()), newECKey(t, elliptic.P256())
+	keySet := newTestKeySet(t, ecJWK("old", &oldKey.PublicKey))
+	a := newTestAuthenticator(t, keySet.URL)
+
+	if err := a.verify(signToken(t, jwt.SigningMethodES256, oldKey, "old", validClaims())); err != nil {
+		t.Fatalf("verify old key: %v", err)
+	}
+	keySet.set(ecJWK("new", &newKey.PublicKey))
+
+	// Unknown kids refetch at most once per minJWKSRefreshInterval.
+	if err := a.verify(signToken(t, jwt.SigningMethodES256, newKey, "new", validClaims())); err == nil {
+		t.Fatal("new key verified within the refresh interval")
+	}
+	if n := keySet.fetches.Load(); n != 1 {
+		t.Fatalf("fetches = %d, want 1", n)
+	}
+
+	a.jwks.mu.Lock()
+	a.jwks.fetchedAt = time.Now().Add(-minJWKSRefreshInterval)
+	a.jwks.mu.Unlock()
+	if err := a.verify(signToken(t, jwt.SigningMethodES256, newKey, "new", validClaims())); err != nil {
+		t.Fatalf("verify new key: %v", err)
+	}
+	if err := a.verify(signToken(t, jwt.SigningMethodES256, oldKey, "old", validClaims())); err == nil {
+		t.Fatal("rotated out key still verifies")
+	}
+	if n := keySet.fetches.Load(); n != 2 {
+		t.Errorf("fetches = %d, want 2", n)
+	}
+}
+
+func TestJWKSSharesInFlightFetch(t *testing.T) {
+	key := newECKey(t, elliptic.P256())
+	release := make(chan struct{})
+	var fetches atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		<-release
+		_ = json.NewEncoder(w).Encode(map[string]any{"keys": []jwk{ecJWK("k", &key.PublicKey)}})
+	}))
+	defer srv.Close()
+	s := newJWKS(srv.URL, nil, time.Hour)
+
+	// A caller giving up returns at once without cancelling the shared fetch.
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := s.key(ctx, "k"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("key with expired context: %v, want deadline exceeded", err)
+	}
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 10)
+	for range 10 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.key(context.Background(), "k")
+			errs <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(release)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Errorf("key: %v", err)
+		}
+	}
+	if n := fetches.Load(); n != 1 {
+		t.Errorf("fetches = %d, want 1", n)
+	}
+}
+
+func TestJWKSWatchStopsWithContext(t *testing.T) {
+	keySet := newTestKeySet(t, ecJWK("k", &newECKey(t, elliptic.P256()).PublicKey))
+	s := newJWKS(keySet.URL, nil, 10*time.Millisecond)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		s.watch(ctx)
+		close(done)
+	}()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("watch did not stop")
+	}
+	if n := keySet.fetches.Load(); n < 2 {
+		t.Errorf("fetches = %d, want periodic refreshes", n)
+	}
+}
+
+func TestAuthMiddleware(t *testing.T) {
+	key := newECKey(t, elliptic.P256())
+	keySet := newTestKeySet(t, ecJWK("k", &key.PublicKey))
+	s, err := New(WithAuth(AuthConfig{
+		JWKSURL:   keySet.URL,
+		Issuer:    testIssuer,
+		Audience:  []string{"billing", testAudience},
+		SkipPaths: []string{"/public"},
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := s.Router()
+	app.Get("/me", func(c fiber.Ctx) error {
+		claims, _ := ClaimsFromContext(c.Context())
+		return c.SendString(claims.Subject)
+	})
+	app.Get("/public", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	})
+
+	with := func(change func(jwt.MapClaims)) string {
+		claims := validClaims()
+		change(claims)
+		return "Bearer " + signToken(t, jwt.SigningMethodES256, key, "k", claims)
+	}
+	tests := []struct {
+		name   string
+		path   string
+		header string
+		want   int
+	}{
+		{"valid", "/me", with(func(jwt.MapClaims) {}), fiber.StatusOK},
+		{"expired", "/me", with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-2 * time.Minute).Unix() }), fiber.StatusUnauthorized},
+		{"expired within skew", "/me", with(func(c jwt.MapClaims) { c["exp"] = time.Now().Add(-30 * time.Second).Unix() }), fiber.StatusOK},
+		{"no expiry", "/me", with(func(c jwt.MapClaims) { delete(c, "exp") }), fiber.StatusUnauthorized},
+		{"not yet valid", "/me", with(func(c jwt.MapClaims) { c["nbf"] = time.Now().Add(time.Hour).Unix() }), fiber.StatusUnauthorized},
+		{"other audience", "/me", with(func(c jwt.MapClaims) { c["aud"] = "inventory" }), fiber.StatusUnauthorized},
+		{"any configured audience", "/me", with(func(c jwt.MapClaims) { c["aud"] = []string{"inventory", "billing"} }), fiber.StatusOK},
+		{"other issuer", "/me", with(func(c jwt.MapClaims) { c["iss"] = "https://evil.example" }), fiber.StatusUnauthorized},
+		{"missing token", "/me", "", fiber.StatusUnauthorized},
+		{"basic auth", "/me", "Basic dXNlcjpwYXNz", fiber.StatusUnauthorized},
+		{"malformed token", "/me", "Bearer not.a.jwt", fiber.StatusUnauthorized},
+		{"skipped path", "/public", "", fiber.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.header != "" {
+				req.Header.Set(fiber.HeaderAuthorization, tt.header)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.want)
+			}
+			if tt.want == fiber.StatusUnauthorized && res.Header.Get(fiber.HeaderWWWAuthenticate) == "" {
+				t.Error("missing WWW-Authenticate header")
+			}
+		})
+	}
+}
+
+func TestRequireScopesAndRole(t *testing.T) {
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := s.Router()
+	ok := func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusNoContent)
+	}
+
+	tests := []struct {
+		name   string
+		claims *Claims
+		guard  fiber.Handler
+		want   int
+	}{
+		{"scopes granted", &Claims{Scopes: []string{"orders:read", "orders:write"}}, RequireScopes("orders:read", "orders:write"), fiber.StatusNoContent},
+		{"scope missing", &Claims{Scopes: []string{"orders:read"}}, RequireScopes("orders:read", "orders:write"), fiber.StatusForbidden},
+		{"scopes without token", nil, RequireScopes("orders:read"), fiber.StatusUnauthorized},
+		{"role granted", &Claims{Roles: []string{"support"}}, RequireRole("admin", "support"), fiber.StatusNoContent},
+		{"role missing", &Claims{Roles: []string{"viewer"}}, RequireRole("admin", "support"), fiber.StatusForbidden},
+		{"role without token", nil, RequireRole("admin"), fiber.StatusUnauthorized},
+	}
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := "/" + string(rune('a'+i))
+			authenticate := func(c fiber.Ctx) error {
+				if tt.claims != nil {
+					c.SetContext(ContextWithClaims(c.Context(), tt.claims))
+				}
+				return c.Next()
+			}
+			app.Get(path, ok, authenticate, tt.guard)
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer res.Body.Close()
+			if res.StatusCode != tt.want {
+				t.Errorf("status = %d, want %d", res.StatusCode, tt.want)
+			}
+		})
+	}
+}
+
+func TestClaimsScopeFormats(t *testing.T) {
+	tests := []struct {
+		name   string
+		claims string
+		scopes []string
+		roles  []string
+	}{
+		{"scope string", `{"scope":"a b"}`, []string{"a", "b"}, nil},
+		{"scp list", `{"scp":["a","b"]}`, []string{"a", "b"}, nil},
+		{"scp string over scope", `{"scope":"a","scp":"b c"}`, []string{"b", "c"}, nil},
+		{"roles list", `{"roles":["admin"]}`, nil, []string{"admin"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var claims Claims
+			if err := json.Unmarshal([]byte(tt.claims), &claims); err != nil {
+				t.Fatal(err)
+			}
+			for _, scope := range tt.scopes {
+				if !claims.HasScope(scope) {
+					t.Errorf("missing scope %q in %v", scope, claims.Scopes)
+				}
+			}
+			if len(claims.Scopes) != len(tt.scopes) {
+				t.Errorf("scopes = %v, want %v", claims.Scopes, tt.scopes)
+			}
+			for _, role := range tt.roles {
+				if !claims.HasRole(role) {
+					t.Errorf("missing role %q in %v", role, claims.Roles)
+				}
+			}
+		})
+	}
+}
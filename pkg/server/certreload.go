@@ -0,0 +1,189 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// TLSFiles serves TLS from files that are polled and reloaded in place,
+// e.g. a cert-manager secret mounted into the pod.
+type TLSFiles struct {
+	CertFile string
+	KeyFile  string
+	// ClientCAFile requires and verifies client certificates against its bundle.
+	ClientCAFile string
+	// CRLFile rejects client certificates revoked by the CRL, in PEM or DER.
+	// Once the CRL is past its next update every client certificate signed by
+	// its issuer is rejected, since the list can no longer vouch for them.
+	CRLFile string
+	// ReloadInterval defaults to 30 seconds.
+	ReloadInterval time.Duration
+}
+
+type tlsMaterial struct {
+	certificate tls.Certificate
+	clientCAs   *x509.CertPool
+	crl         *x509.RevocationList
+	revoked     map[string]bool
+}
+
+type certReloader struct {
+	files    TLSFiles
+	current  atomic.Pointer[tlsMaterial]
+	checksum [sha256.Size]byte
+}
+
+func newCertReloader(files TLSFiles) (*certReloader, error) {
+	if files.CertFile == "" || files.KeyFile == "" {
+		return nil, errors.New("server: tls cert and key files are required")
+	}
+	if files.ReloadInterval == 0 {
+		files.ReloadInterval = 30 * time.Second
+	}
+	r := &certReloader{files: files}
+	if _, err := r.reload(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// watch reloads the files until ctx is done. A failed reload keeps serving the
+// previous material, since secret updates may land one file at a time.
+func (r *certReloader) watch(ctx context.Context) {
+	ticker := time.NewTicker(r.files.ReloadInterval)
+	defer ticker.Stop()
+	for {
+		r.current.Load().logExpiredCRL(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		changed, err := r.reload()
+		if err != nil {
+			slog.ErrorContext(ctx, "Failed to reload TLS files", slog.Any("error", err))
+			continue
+		}
+		if changed {
+			leaf := r.current.Load().certificate.Leaf
+			slog.InfoContext(ctx, "Reloaded TLS files",
+				slog.String("subject", leaf.Subject.String()),
+				slog.Time("not_after", leaf.NotAfter),
+			)
+		}
+	}
+}
+
+// logExpiredCRL reports a CRL that is no longer refreshed, on every poll
+// until a new one lands, because checkRevocation fails closed on it.
+func (m *tlsMaterial) logExpiredCRL(ctx context.Context) {
+	if m.crl == nil || !crlExpired(m.crl, time.Now()) {
+		return
+	}
+	slog.ErrorContext(ctx, "CRL is past its next update, rejecting client certificates",
+		slog.String("issuer", m.crl.Issuer.String()),
+		slog.Time("next_update", m.crl.NextUpdate),
+	)
+}
+
+func crlExpired(crl *x509.RevocationList, now time.Time) bool {
+	return !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate)
+}
+
+func (r *certReloader) reload() (bool, error) {
+	paths := []string{r.files.CertFile, r.files.KeyFile, r.files.ClientCAFile, r.files.CRLFile}
+	contents := make([][]byte, len(paths))
+	hash := sha256.New()
+	for i, path := range paths {
+		if path == "" {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return false, err
+		}
+		contents[i] = b
+		hash.Write(b)
+	}
+	var checksum [sha256.Size]byte
+	hash.Sum(checksum[:0])
+	if checksum == r.checksum {
+		return false, nil
+	}
+
+	certificate, err := tls.X509KeyPair(contents[0], contents[1])
+	if err != nil {
+		return false, err
+	}
+	material := &tlsMaterial{certificate: certificate}
+	if r.files.ClientCAFile != "" {
+		material.clientCAs = x509.NewCertPool()
+		if !material.clientCAs.AppendCertsFromPEM(contents[2]) {
+			return false, errors.New("tls: failed to parse client CA file")
+		}
+	}
+	if r.files.CRLFile != "" {
+		der := contents[3]
+		if block, _ := pem.Decode(der); block != nil {
+			der = block.Bytes
+		}
+		if material.crl, err = x509.ParseRevocationList(der); err != nil {
+			return false, fmt.Errorf("tls: parse crl: %w", err)
+		}
+		material.revoked = map[string]bool{}
+		for _, entry := range material.crl.RevokedCertificateEntries {
+			material.revoked[entry.SerialNumber.String()] = true
+		}
+	}
+	r.current.Store(material)
+	r.checksum = checksum
+	return true, nil
+}
+
+// apply serves every handshake from the material current at that moment, so a
+// reload swaps the certificate, client CAs and CRL together.
+func (r *certReloader) apply(tlsConfig *tls.Config) {
+	base := tlsConfig.Clone()
+	tlsConfig.Certificates = []tls.Certificate{r.current.Load().certificate}
+	tlsConfig.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+		material := r.current.Load()
+		config := base.Clone()
+		config.Certificates = []tls.Certificate{material.certificate}
+		if material.clientCAs != nil {
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+			config.ClientCAs = material.clientCAs
+		}
+		if material.crl != nil {
+			config.VerifyPeerCertificate = material.checkRevocation
+		}
+		return config, nil
+	}
+}
+
+func (m *tlsMaterial) checkRevocation(_ [][]byte, verifiedChains [][]*x509.Certificate) error {
+	for _, chain := range verifiedChains {
+		for i := 0; i+1 < len(chain); i++ {
+			cert, issuer := chain[i], chain[i+1]
+			if !bytes.Equal(cert.RawIssuer, m.crl.RawIssuer) || m.crl.CheckSignatureFrom(issuer) != nil {
+				continue
+			}
+			if crlExpired(m.crl, time.Now()) {
+				return fmt.Errorf("tls: crl expired at %s", m.crl.NextUpdate.Format(time.RFC3339))
+			}
+			if m.revoked[cert.SerialNumber.String()] {
+				return fmt.Errorf("tls: certificate %s is revoked", cert.SerialNumber)
+			}
+		}
+	}
+	return nil
+}
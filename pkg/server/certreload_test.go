@@ -0,0 +1,232 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testCA issues server and client certificates and CRLs from a throwaway root.
+type testCA struct {
+	t      *testing.T
+	cert   *x509.Certificate
+	key    crypto.Signer
+	serial int64
+}
+
+func newTestCA(t *testing.T) *testCA {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test client ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(24 * time.Hour),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &testCA{t: t, cert: cert, key: key, serial: 1}
+}
+
+// issue returns a leaf for usage, e.g. server or client auth.
+func (ca *testCA) issue(usage x509.ExtKeyUsage) tls.Certificate {
+	ca.t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	ca.serial++
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(ca.serial),
+		Subject:      pkix.Name{CommonName: "leaf"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(24 * time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key, Leaf: leaf}
+}
+
+func (ca *testCA) crl(nextUpdate time.Time, revoked ...*x509.Certificate) []byte {
+	ca.t.Helper()
+	template := &x509.RevocationList{
+		Number:     big.NewInt(1),
+		ThisUpdate: time.Now().Add(-2 * time.Hour),
+		NextUpdate: nextUpdate,
+	}
+	for _, cert := range revoked {
+		template.RevokedCertificateEntries = append(template.RevokedCertificateEntries, x509.RevocationListEntry{
+			SerialNumber:   cert.SerialNumber,
+			RevocationTime: time.Now().Add(-time.Hour),
+		})
+	}
+	der, err := x509.CreateRevocationList(rand.Reader, template, ca.cert, ca.key)
+	if err != nil {
+		ca.t.Fatal(err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der})
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	t.Helper()
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// writeKeyPair replaces the files in place, key first, the way a secret
+// update may land one file at a time.
+func writeKeyPair(t *testing.T, files TLSFiles, cert tls.Certificate) {
+	t.Helper()
+	key, err := x509.MarshalPKCS8PrivateKey(cert.PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, files.KeyFile, "PRIVATE KEY", key)
+	writePEM(t, files.CertFile, "CERTIFICATE", cert.Certificate[0])
+}
+
+// handshake runs a TLS handshake against the reloader's config over a pipe,
+// returning the server's error and the certificate the client was served.
+func handshake(t *testing.T, r *certReloader, roots *x509.CertPool, clientCert *tls.Certificate) (*x509.Certificate, error) {
+	t.Helper()
+	serverConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	r.apply(serverConfig)
+	clientConfig := &tls.Config{ServerName: "localhost", RootCAs: roots}
+	if clientCert != nil {
+		clientConfig.Certificates = []tls.Certificate{*clientCert}
+	}
+	serverConn, clientConn := net.Pipe()
+	server := tls.Server(serverConn, serverConfig)
+	client := tls.Client(clientConn, clientConfig)
+	served := make(chan *x509.Certificate, 1)
+	go func() {
+		defer client.Close()
+		if err := client.Handshake(); err != nil {
+			served <- nil
+			return
+		}
+		served <- client.ConnectionState().PeerCertificates[0]
+		// TLS 1.3 finishes the client side first; wait for the server's verdict.
+		_, _ = client.Read(make([]byte, 1))
+	}()
+	err := server.Handshake()
+	server.Close()
+	return <-served, err
+}
+
+func TestCertReloaderRevocation(t *testing.T) {
+	ca := newTestCA(t)
+	serverCert := ca.issue(x509.ExtKeyUsageServerAuth)
+	goodClient := ca.issue(x509.ExtKeyUsageClientAuth)
+	revokedClient := ca.issue(x509.ExtKeyUsageClientAuth)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+
+	tests := []struct {
+		name       string
+		nextUpdate time.Time
+		client     *tls.Certificate
+		wantErr    string
+	}{
+		{"valid client", time.Now().Add(time.Hour), &goodClient, ""},
+		{"revoked client", time.Now().Add(time.Hour), &revokedClient, "is revoked"},
+		{"no client certificate", time.Now().Add(time.Hour), nil, "didn't provide a certificate"},
+		{"expired crl", time.Now().Add(-time.Minute), &goodClient, "crl expired"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := TLSFiles{
+				CertFile:     filepath.Join(dir, "tls.crt"),
+				KeyFile:      filepath.Join(dir, "tls.key"),
+				ClientCAFile: filepath.Join(dir, "ca.crt"),
+				CRLFile:      filepath.Join(dir, "ca.crl"),
+			}
+			writeKeyPair(t, files, serverCert)
+			writePEM(t, files.ClientCAFile, "CERTIFICATE", ca.cert.Raw)
+			if err := os.WriteFile(files.CRLFile, ca.crl(tt.nextUpdate, revokedClient.Leaf), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			r, err := newCertReloader(files)
+			if err != nil {
+				t.Fatal(err)
+			}
+			_, err = handshake(t, r, roots, tt.client)
+			if tt.wantErr == "" && err != nil || tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)) {
+				t.Errorf("handshake error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestCertReloaderServesRewrittenFiles(t *testing.T) {
+	ca := newTestCA(t)
+	first, second := ca.issue(x509.ExtKeyUsageServerAuth), ca.issue(x509.ExtKeyUsageServerAuth)
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	dir := t.TempDir()
+	files := TLSFiles{
+		CertFile:       filepath.Join(dir, "tls.crt"),
+		KeyFile:        filepath.Join(dir, "tls.key"),
+		ReloadInterval: 10 * time.Millisecond,
+	}
+	writeKeyPair(t, files, first)
+	r, err := newCertReloader(files)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go r.watch(ctx)
+
+	if served, err := handshake(t, r, roots, nil); err != nil || served.SerialNumber.Cmp(first.Leaf.SerialNumber) != 0 {
+		t.Fatalf("served %v, %v, want the first certificate", served, err)
+	}
+	writeKeyPair(t, files, second)
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		served, err := handshake(t, r, roots, nil)
+		if err == nil && served.SerialNumber.Cmp(second.Leaf.SerialNumber) == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("still served %v, %v after the poll interval, want the second certificate", served, err)
+		}
+		time.Sleep(files.ReloadInterval)
+	}
+}
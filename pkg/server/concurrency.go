@@ -0,0 +1,215 @@
+package server
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type ConcurrencyAlgorithm int
+
+const (
+	// AIMD grows the limit by one while it is in use and cuts it by
+	// BackoffRatio when a request exceeds LatencyThreshold or times out.
+	AIMD ConcurrencyAlgorithm = iota
+	// Gradient scales the limit by the ratio of long-term to recent latency,
+	// shrinking it as queueing inflates response times.
+	Gradient
+)
+
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// priorityShares is the fraction of the limit each class may fill, so lower
+// classes are shed first and high priority traffic keeps headroom.
+var priorityShares = map[Priority]float64{
+	PriorityLow:    0.7,
+	PriorityNormal: 0.9,
+	PriorityHigh:   1,
+}
+
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityHigh:
+		return "high"
+	}
+	return "normal"
+}
+
+type ConcurrencyLimitConfig struct {
+	Algorithm ConcurrencyAlgorithm
+	// InitialLimit defaults to 20, MinLimit to 1 and MaxLimit to 1000.
+	InitialLimit int
+	MinLimit     int
+	MaxLimit     int
+	// LatencyThreshold is the AIMD latency treated as overload. Defaults to 1 second.
+	LatencyThreshold time.Duration
+	// BackoffRatio multiplies the limit on overload. Defaults to 0.9.
+	BackoffRatio float64
+	// Priority classifies requests; all requests are PriorityNormal when nil.
+	Priority func(c fiber.Ctx) Priority
+}
+
+// PriorityByHeader reads "low", "normal" or "high" from a request header.
+func PriorityByHeader(name string) func(c fiber.Ctx) Priority {
+	return func(c fiber.Ctx) Priority {
+		switch strings.ToLower(c.Get(name)) {
+		case "low":
+			return PriorityLow
+		case "high":
+			return PriorityHigh
+		}
+		return PriorityNormal
+	}
+}
+
+// PriorityByPath classifies requests by the longest matching path prefix.
+// Routes are not resolved yet when the limiter runs, so prefixes stand in for them.
+func PriorityByPath(prefixes map[string]Priority) func(c fiber.Ctx) Priority {
+	return func(c fiber.Ctx) Priority {
+		path := c.Path()
+		priority, longest := PriorityNormal, -1
+		for prefix, p := range prefixes {
+			if strings.HasPrefix(path, prefix) && len(prefix) > longest {
+				priority, longest = p, len(prefix)
+			}
+		}
+		return priority
+	}
+}
+
+type concurrencyLimiter struct {
+	cfg ConcurrencyLimitConfig
+
+	mu       sync.Mutex
+	limit    float64
+	inFlight int
+	longRTT  float64
+	shortRTT float64
+
+	limitGauge prometheus.Gauge
+	rejected   *prometheus.CounterVec
+}
+
+func newConcurrencyLimiter(cfg ConcurrencyLimitConfig, registry *prometheus.Registry) (*concurrencyLimiter, error) {
+	if cfg.Algorithm != AIMD && cfg.Algorithm != Gradient {
+		return nil, errors.New("server: unknown concurrency limit algorithm")
+	}
+	if cfg.MinLimit <= 0 {
+		cfg.MinLimit = 1
+	}
+	if cfg.MaxLimit <= 0 {
+		cfg.MaxLimit = 1000
+	}
+	if cfg.InitialLimit <= 0 {
+		cfg.InitialLimit = 20
+	}
+	if cfg.MinLimit > cfg.InitialLimit || cfg.InitialLimit > cfg.MaxLimit {
+		return nil, errors.New("server: concurrency limits must satisfy min <= initial <= max")
+	}
+	if cfg.LatencyThreshold <= 0 {
+		cfg.LatencyThreshold = time.Second
+	}
+	if cfg.BackoffRatio <= 0 || cfg.BackoffRatio >= 1 {
+		cfg.BackoffRatio = 0.9
+	}
+	if cfg.Priority == nil {
+		cfg.Priority = func(fiber.Ctx) Priority { return PriorityNormal }
+	}
+	l := &concurrencyLimiter{
+		cfg:   cfg,
+		limit: float64(cfg.InitialLimit),
+		limitGauge: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_server_concurrency_limit",
+			Help: "Current adaptive limit on concurrent HTTP requests.",
+		}),
+		rejected: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_concurrency_rejected_total",
+			Help: "Total number of HTTP requests shed by the concurrency limiter.",
+		}, []string{"priority"}),
+	}
+	l.limitGauge.Set(l.limit)
+	if registry != nil {
+		for _, collector := range []prometheus.Collector{l.limitGauge, l.rejected} {
+			if err := registry.Register(collector); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return l, nil
+}
+
+// middleware sheds requests over the limit with an immediate 503 instead of
+// queueing them behind requests that are already slow.
+func (l *concurrencyLimiter) middleware(c fiber.Ctx) error {
+	priority := l.cfg.Priority(c)
+	if !l.acquire(priority) {
+		l.rejected.WithLabelValues(priority.String()).Inc()
+		c.Set(fiber.HeaderRetryAfter, "1")
+		return NewProblem(fiber.StatusServiceUnavailable, "server is overloaded")
+	}
+	start := time.Now()
+	// Deferred so a panic unwinding to an outer recover still frees the slot.
+	defer func() {
+		status := c.Response().StatusCode()
+		l.release(time.Since(start), status == fiber.StatusServiceUnavailable || status == fiber.StatusGatewayTimeout)
+	}()
+	return next(c)
+}
+
+func (l *concurrencyLimiter) acquire(priority Priority) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if float64(l.inFlight) >= math.Max(1, l.limit*priorityShares[priority]) {
+		return false
+	}
+	l.inFlight++
+	return true
+}
+
+func (l *concurrencyLimiter) release(rtt time.Duration, dropped bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	inFlight := l.inFlight
+	l.inFlight--
+	switch l.cfg.Algorithm {
+	case AIMD:
+		if dropped || rtt > l.cfg.LatencyThreshold {
+			l.limit *= l.cfg.BackoffRatio
+		} else if float64(inFlight)*2 >= l.limit {
+			l.limit++
+		}
+	case Gradient:
+		// Instant responses measure as zero on coarse clocks, which would
+		// turn the ratio below into 0/0 and the limit into NaN.
+		sample := math.Max(float64(rtt), float64(time.Microsecond))
+		if l.longRTT == 0 {
+			l.longRTT, l.shortRTT = sample, sample
+		}
+		l.longRTT += (sample - l.longRTT) / 100
+		l.shortRTT += (sample - l.shortRTT) / 5
+		// Allow recent latency up to 1.5x the baseline before shrinking.
+		gradient := math.Max(0.5, math.Min(1, 1.5*l.longRTT/l.shortRTT))
+		target := l.limit*gradient + math.Sqrt(l.limit)
+		if dropped {
+			// Without the square root of queueing headroom, which would
+			// outweigh the backoff below a limit of 100.
+			target = l.limit * math.Min(gradient, l.cfg.BackoffRatio)
+		}
+		l.limit = 0.8*l.limit + 0.2*target
+	}
+	l.limit = math.Max(float64(l.cfg.MinLimit), math.Min(float64(l.cfg.MaxLimit), l.limit))
+	l.limitGauge.Set(l.limit)
+}
@@ -0,0 +1,223 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func newTestConcurrencyLimiter(t *testing.T, cfg ConcurrencyLimitConfig) *concurrencyLimiter {
+	t.Helper()
+	l, err := newConcurrencyLimiter(cfg, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func TestConcurrencyLimiterAcquire(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    float64
+		inFlight int
+		priority Priority
+		want     bool
+	}{
+		{"low under its share", 10, 6, PriorityLow, true},
+		{"low at its share", 10, 7, PriorityLow, false},
+		{"normal under its share", 10, 8, PriorityNormal, true},
+		{"normal at its share", 10, 9, PriorityNormal, false},
+		{"high uses the full limit", 10, 9, PriorityHigh, true},
+		{"high at the limit", 10, 10, PriorityHigh, false},
+		{"low always gets one slot", 1, 0, PriorityLow, true},
+		{"low beyond the one slot", 1, 1, PriorityLow, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestConcurrencyLimiter(t, ConcurrencyLimitConfig{})
+			l.limit, l.inFlight = tt.limit, tt.inFlight
+			if got := l.acquire(tt.priority); got != tt.want {
+				t.Fatalf("acquire = %t, want %t", got, tt.want)
+			}
+			wantInFlight := tt.inFlight
+			if tt.want {
+				wantInFlight++
+			}
+			if l.inFlight != wantInFlight {
+				t.Errorf("in flight = %d, want %d", l.inFlight, wantInFlight)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterAIMD(t *testing.T) {
+	tests := []struct {
+		name     string
+		limit    float64
+		inFlight int
+		rtt      time.Duration
+		dropped  bool
+		want     float64
+	}{
+		{"grows while half the limit is in use", 10, 5, time.Millisecond, false, 11},
+		{"holds while mostly idle", 10, 4, time.Millisecond, false, 10},
+		{"backs off on a dropped request", 10, 5, time.Millisecond, true, 9},
+		{"backs off above the latency threshold", 10, 5, 2 * time.Second, false, 9},
+		{"stops at the minimum", 2, 1, time.Millisecond, true, 2},
+		{"stops at the maximum", 50, 50, time.Millisecond, false, 50},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestConcurrencyLimiter(t, ConcurrencyLimitConfig{MinLimit: 2, InitialLimit: 10, MaxLimit: 50})
+			l.limit, l.inFlight = tt.limit, tt.inFlight
+			l.release(tt.rtt, tt.dropped)
+			if math.Abs(l.limit-tt.want) > 1e-9 {
+				t.Errorf("limit = %v, want %v", l.limit, tt.want)
+			}
+			if l.inFlight != tt.inFlight-1 {
+				t.Errorf("in flight = %d, want %d", l.inFlight, tt.inFlight-1)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimiterGradient(t *testing.T) {
+	type sample struct {
+		rtt     time.Duration
+		dropped bool
+		times   int
+	}
+	tests := []struct {
+		name    string
+		samples []sample
+		// The limit starts at 20 and must end within [wantMin, wantMax].
+		wantMin, wantMax float64
+	}{
+		{
+			name:    "first sample grows by the square root",
+			samples: []sample{{rtt: 10 * time.Millisecond, times: 1}},
+			wantMin: 20.894, wantMax: 20.895,
+		},
+		{
+			name:    "steady latency grows to the maximum",
+			samples: []sample{{rtt: 10 * time.Millisecond, times: 200}},
+			wantMin: 100, wantMax: 100,
+		},
+		{
+			name: "rising latency shrinks the limit",
+			samples: []sample{
+				{rtt: 10 * time.Millisecond, times: 200},
+				{rtt: 100 * time.Millisecond, times: 20},
+			},
+			wantMin: 1, wantMax: 80,
+		},
+		{
+			name:    "drops shrink the limit",
+			samples: []sample{{rtt: 10 * time.Millisecond, dropped: true, times: 20}},
+			wantMin: 1, wantMax: 19,
+		},
+		{
+			name:    "zero latency stays finite",
+			samples: []sample{{rtt: 0, times: 50}},
+			wantMin: 21, wantMax: 100,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newTestConcurrencyLimiter(t, ConcurrencyLimitConfig{Algorithm: Gradient, InitialLimit: 20, MaxLimit: 100})
+			for _, s := range tt.samples {
+				for range s.times {
+					l.inFlight++
+					l.release(s.rtt, s.dropped)
+				}
+			}
+			if math.IsNaN(l.limit) || l.limit < tt.wantMin || l.limit > tt.wantMax {
+				t.Errorf("limit = %v, want within [%v, %v]", l.limit, tt.wantMin, tt.wantMax)
+			}
+		})
+	}
+}
+
+func TestConcurrencyLimitMiddleware(t *testing.T) {
+	s, err := New(WithConcurrencyLimit(ConcurrencyLimitConfig{
+		InitialLimit: 10,
+		Priority:     PriorityByHeader("X-Priority"),
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := s.Router()
+	entered, release := make(chan struct{}), make(chan struct{})
+	app.Get("/slow", func(c fiber.Ctx) error {
+		entered <- struct{}{}
+		<-release
+		return c.SendStatus(fiber.StatusOK)
+	})
+	app.Get("/fast", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	send := func(path, priority string) int {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Priority", priority)
+		res, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+		if err != nil {
+			t.Error(err)
+			return 0
+		}
+		res.Body.Close()
+		return res.StatusCode
+	}
+
+	// Fill the low priority share of 7 slots.
+	var wg sync.WaitGroup
+	for range 7 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			send("/slow", "high")
+		}()
+		<-entered
+	}
+	if got := send("/fast", "low"); got != fiber.StatusServiceUnavailable {
+		t.Errorf("low priority status = %d, want %d", got, fiber.StatusServiceUnavailable)
+	}
+	if got := send("/fast", "normal"); got != fiber.StatusOK {
+		t.Errorf("normal priority status = %d, want %d", got, fiber.StatusOK)
+	}
+	close(release)
+	wg.Wait()
+	if got := send("/fast", "low"); got != fiber.StatusOK {
+		t.Errorf("low priority status after release = %d, want %d", got, fiber.StatusOK)
+	}
+}
+
+func TestConcurrencyLimitReleasesOnPanic(t *testing.T) {
+	l := newTestConcurrencyLimiter(t, ConcurrencyLimitConfig{})
+	app := fiber.New()
+	app.Use(func(c fiber.Ctx) (err error) {
+		defer func() {
+			if recover() != nil {
+				err = c.SendStatus(fiber.StatusInternalServerError)
+			}
+		}()
+		return c.Next()
+	}, l.middleware)
+	app.Get("/", func(fiber.Ctx) error {
+		panic("boom")
+	})
+	res, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res.Body.Close()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.inFlight != 0 {
+		t.Errorf("in flight after panic = %d, want 0", l.inFlight)
+	}
+}
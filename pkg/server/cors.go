@@ -0,0 +1,103 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/cors"
+	"github.com/nphiro/mesh/pkg/env"
+)
+
+type CORSConfig struct {
+	// AllowOrigins lists exact origins ("https://app.example.com"), wildcard
+	// subdomains ("https://*.example.com") or "*" for any origin.
+	AllowOrigins        []string
+	AllowOriginPatterns []*regexp.Regexp
+	AllowOriginFunc     func(origin string) bool
+
+	AllowMethods     []string
+	AllowHeaders     []string
+	ExposeHeaders    []string
+	AllowCredentials bool
+	MaxAge           time.Duration
+}
+
+// defaultCORSConfig keeps development open to any origin,
+// while production only allows what a service configures explicitly.
+func defaultCORSConfig() CORSConfig {
+	if env.InProduction() {
+		return CORSConfig{}
+	}
+	return CORSConfig{AllowOrigins: []string{"*"}}
+}
+
+func corsMiddleware(cfg CORSConfig) (fiber.Handler, error) {
+	if slices.Contains(cfg.AllowOrigins, "*") {
+		if cfg.AllowCredentials {
+			return nil, errors.New("cors: credentials cannot be allowed for any origin")
+		}
+		return cors.New(corsConfig(cfg, []string{"*"}, nil)), nil
+	}
+
+	var (
+		exact     []string
+		wildcards []string
+	)
+	for _, origin := range cfg.AllowOrigins {
+		origin = strings.ToLower(strings.TrimSpace(origin))
+		scheme, host, ok := strings.Cut(origin, "://")
+		if !ok || scheme == "" || host == "" {
+			return nil, fmt.Errorf("cors: invalid origin %q", origin)
+		}
+		if suffix, ok := strings.CutPrefix(host, "*."); ok {
+			wildcards = append(wildcards, scheme+"://."+suffix)
+			continue
+		}
+		if u, err := url.Parse(origin); err != nil || u.Path != "" {
+			return nil, fmt.Errorf("cors: invalid origin %q", origin)
+		}
+		exact = append(exact, origin)
+	}
+
+	allowOrigin := func(origin string) bool {
+		lower := strings.ToLower(origin)
+		if slices.Contains(exact, lower) {
+			return true
+		}
+		for _, wildcard := range wildcards {
+			scheme, suffix, _ := strings.Cut(wildcard, "://")
+			if strings.HasPrefix(lower, scheme+"://") && strings.HasSuffix(lower, suffix) {
+				return true
+			}
+		}
+		for _, pattern := range cfg.AllowOriginPatterns {
+			if pattern.MatchString(origin) {
+				return true
+			}
+		}
+		return cfg.AllowOriginFunc != nil && cfg.AllowOriginFunc(origin)
+	}
+	return cors.New(corsConfig(cfg, nil, allowOrigin)), nil
+}
+
+func corsConfig(cfg CORSConfig, allowOrigins []string, allowOriginsFunc func(string) bool) cors.Config {
+	maxAge := int(cfg.MaxAge.Seconds())
+	if cfg.MaxAge < 0 {
+		maxAge = -1
+	}
+	return cors.Config{
+		AllowOrigins:     allowOrigins,
+		AllowOriginsFunc: allowOriginsFunc,
+		AllowMethods:     cfg.AllowMethods,
+		AllowHeaders:     cfg.AllowHeaders,
+		ExposeHeaders:    cfg.ExposeHeaders,
+		AllowCredentials: cfg.AllowCredentials,
+		MaxAge:           maxAge,
+	}
+}
@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// TimeoutHeader is read in grpc-timeout format, matching what pkg/client sends.
+const TimeoutHeader = "X-Request-Timeout"
+
+var timeoutUnits = map[byte]time.Duration{
+	'H': time.Hour,
+	'M': time.Minute,
+	'S': time.Second,
+	'm': time.Millisecond,
+	'u': time.Microsecond,
+	'n': time.Nanosecond,
+}
+
+// grpc-timeout values have at most 8 digits.
+const maxTimeoutDigits = 8
+
+// maxPropagatedTimeout caps the budget a caller can hand the server, so a
+// huge value cannot keep a request alive longer than any real client waits.
+const maxPropagatedTimeout = 5 * time.Minute
+
+// parseTimeout saturates at the largest Duration rather than overflowing,
+// e.g. for "99999999H".
+func parseTimeout(value string) (time.Duration, bool) {
+	if len(value) < 2 || len(value) > maxTimeoutDigits+1 {
+		return 0, false
+	}
+	unit, ok := timeoutUnits[value[len(value)-1]]
+	if !ok {
+		return 0, false
+	}
+	n, err := strconv.ParseInt(value[:len(value)-1], 10, 64)
+	if err != nil || n <= 0 {
+		return 0, false
+	}
+	if n > int64(math.MaxInt64/unit) {
+		return math.MaxInt64, true
+	}
+	return time.Duration(n) * unit, true
+}
+
+func deadlineMiddleware(c fiber.Ctx) error {
+	timeout, ok := parseTimeout(c.Get(TimeoutHeader))
+	if !ok {
+		return c.Next()
+	}
+	ctx, cancel := context.WithTimeout(c.Context(), min(timeout, maxPropagatedTimeout))
+	defer cancel()
+	c.SetContext(ctx)
+	return c.Next()
+}
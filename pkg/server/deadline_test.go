@@ -0,0 +1,92 @@
+package server
+
+import (
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+func TestParseTimeout(t *testing.T) {
+	tests := []struct {
+		value  string
+		want   time.Duration
+		wantOK bool
+	}{
+		{"150m", 150 * time.Millisecond, true},
+		{"2S", 2 * time.Second, true},
+		{"3M", 3 * time.Minute, true},
+		{"1H", time.Hour, true},
+		{"250u", 250 * time.Microsecond, true},
+		{"10n", 10, true},
+		{"99999999m", 99_999_999 * time.Millisecond, true},
+		{"99999999H", math.MaxInt64, true},
+		{"2562048H", math.MaxInt64, true},
+		{"2562047H", 2_562_047 * time.Hour, true},
+		{"100000000m", 0, false},
+		{"000000001S", 0, false},
+		{"", 0, false},
+		{"m", 0, false},
+		{"10", 0, false},
+		{"10s", 0, false},
+		{"0m", 0, false},
+		{"-5m", 0, false},
+		{"1.5S", 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, ok := parseTimeout(tt.value)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("parseTimeout(%q) = %s, %t, want %s, %t", tt.value, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestDeadlineMiddleware(t *testing.T) {
+	tests := []struct {
+		name    string
+		timeout string
+		// want is the budget the handler sees; zero means no deadline.
+		want time.Duration
+	}{
+		{"no header", "", 0},
+		{"invalid header", "soon", 0},
+		{"caller budget", "150m", 150 * time.Millisecond},
+		{"capped", "99999999H", maxPropagatedTimeout},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(WithDeadlinePropagation())
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := s.Router()
+			var remaining time.Duration
+			app.Get("/orders", func(c fiber.Ctx) error {
+				if deadline, ok := c.Context().Deadline(); ok {
+					remaining = time.Until(deadline)
+				}
+				return c.SendStatus(fiber.StatusOK)
+			})
+			req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+			if tt.timeout != "" {
+				req.Header.Set(TimeoutHeader, tt.timeout)
+			}
+			res, err := app.Test(req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != fiber.StatusOK {
+				t.Fatalf("status = %d, want %d", res.StatusCode, fiber.StatusOK)
+			}
+			if remaining > tt.want || remaining < tt.want-time.Second {
+				t.Errorf("remaining budget = %s, want about %s", remaining, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/gofiber/fiber/v3"
+)
+
+// StatusCoder lets a typed handler response choose its status code, e.g. 201.
+type StatusCoder interface {
+	StatusCode() int
+}
+
+// Handle adapts a typed function to a fiber handler. The request is bound from
+// path (`uri` tags), query (`query`), headers (`header`) and the JSON body, then
+// validated with `validate` tags before fn runs with the request context.
+// The types and opts also describe the route in the OpenAPI document.
+func Handle[Req, Resp any](fn func(ctx context.Context, req Req) (Resp, error), opts ...DocOption) fiber.Handler {
+	doc := &operationDoc{
+		request:  reflect.TypeFor[Req](),
+		response: reflect.TypeFor[Resp](),
+	}
+	for _, opt := range opts {
+		opt(doc)
+	}
+	handler := func(c fiber.Ctx) error {
+		if describe(c, doc.document) {
+			return nil
+		}
+		var req Req
+		target := any(&req)
+		// Pointer requests are bound and validated through the value they point to.
+		if t := reflect.TypeFor[Req](); t.Kind() == reflect.Pointer {
+			v := reflect.New(t.Elem())
+			reflect.ValueOf(&req).Elem().Set(v)
+			target = v.Interface()
+		}
+		if err := bind(c, target); err != nil {
+			return err
+		}
+		resp, err := fn(c.Context(), req)
+		if err != nil {
+			return err
+		}
+		status := fiber.StatusOK
+		if coder, ok := any(resp).(StatusCoder); ok && !isNilPointer(resp) {
+			status = coder.StatusCode()
+		}
+		if status == fiber.StatusNoContent {
+			return c.SendStatus(status)
+		}
+		return c.Status(status).JSON(resp)
+	}
+	return markDocumented(handler)
+}
+
+// isNilPointer guards StatusCoder methods with value receivers, which panic
+// when called through a nil pointer.
+func isNilPointer(v any) bool {
+	rv := reflect.ValueOf(v)
+	return rv.Kind() == reflect.Pointer && rv.IsNil()
+}
+
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		for _, tag := range []string{"json", "uri", "query", "header"} {
+			if name, _, _ := strings.Cut(field.Tag.Get(tag), ","); name != "" && name != "-" {
+				return name
+			}
+		}
+		return field.Name
+	})
+	return v
+}
+
+type binding struct {
+	in   string
+	bind func(any) error
+}
+
+func bind(c fiber.Ctx, req any) error {
+	if t := reflect.TypeOf(req).Elem(); t.Kind() != reflect.Struct {
+		if len(c.Body()) == 0 {
+			return nil
+		}
+		if err := c.Bind().Body(req); err != nil {
+			return NewProblem(fiber.StatusBadRequest, err.Error())
+		}
+		return nil
+	}
+	binder := c.Bind()
+	binds := []binding{
+		{"path", binder.URI},
+		{"query", binder.Query},
+		{"header", binder.Header},
+	}
+	if len(c.Body()) > 0 {
+		binds = append(binds, binding{"body", binder.Body})
+	}
+	for _, b := range binds {
+		if err := b.bind(req); err != nil {
+			return NewProblem(fiber.StatusBadRequest, fmt.Sprintf("invalid %s: %s", b.in, err))
+		}
+	}
+
+	err := validate.Struct(req)
+	var validationErrs validator.ValidationErrors
+	if !errors.As(err, &validationErrs) {
+		return err
+	}
+	problem := NewProblem(fiber.StatusUnprocessableEntity, "request validation failed")
+	t := reflect.TypeOf(req).Elem()
+	for _, fieldErr := range validationErrs {
+		problem.Errors = append(problem.Errors, FieldError{
+			Field:   fieldPath(fieldErr),
+			In:      fieldLocation(t, fieldErr),
+			Message: validationMessage(fieldErr),
+		})
+	}
+	return problem
+}
+
+// fieldPath drops the leading struct name from the validator namespace.
+func fieldPath(fieldErr validator.FieldError) string {
+	_, path, _ := strings.Cut(fieldErr.Namespace(), ".")
+	return path
+}
+
+func fieldLocation(t reflect.Type, fieldErr validator.FieldError) string {
+	_, structPath, _ := strings.Cut(fieldErr.StructNamespace(), ".")
+	name, _, _ := strings.Cut(structPath, ".")
+	name, _, _ = strings.Cut(name, "[")
+	if field, ok := t.FieldByName(name); ok {
+		for tag, in := range map[string]string{"uri": "path", "query": "query", "header": "header"} {
+			if _, ok := field.Tag.Lookup(tag); ok {
+				return in
+			}
+		}
+	}
+	return "body"
+}
+
+func validationMessage(fieldErr validator.FieldError) string {
+	switch fieldErr.Tag() {
+	case "required", "required_if", "required_unless", "required_with", "required_without":
+		return "is required"
+	case "email":
+		return "must be a valid email address"
+	case "url", "http_url":
+		return "must be a valid URL"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "oneof":
+		return "must be one of: " + fieldErr.Param()
+	case "min", "gte":
+		if fieldErr.Kind() == reflect.String || fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map {
+			return "must have at least " + fieldErr.Param() + " items or characters"
+		}
+		return "must be at least " + fieldErr.Param()
+	case "max", "lte":
+		if fieldErr.Kind() == reflect.String || fieldErr.Kind() == reflect.Slice || fieldErr.Kind() == reflect.Map {
+			return "must have at most " + fieldErr.Param() + " items or characters"
+		}
+		return "must be at most " + fieldErr.Param()
+	case "len":
+		return "must have length " + fieldErr.Param()
+	case "gt":
+		return "must be greater than " + fieldErr.Param()
+	case "lt":
+		return "must be less than " + fieldErr.Param()
+	}
+	if fieldErr.Param() != "" {
+		return fmt.Sprintf("failed %s=%s validation", fieldErr.Tag(), fieldErr.Param())
+	}
+	return fmt.Sprintf("failed %s validation", fieldErr.Tag())
+}
@@ -0,0 +1,149 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/cmlt"
+)
+
+const defaultHealthCheckTimeout = 2 * time.Second
+
+type HealthChecker interface {
+	CheckHealth(ctx context.Context) error
+}
+
+type HealthCheckerFunc func(ctx context.Context) error
+
+func (f HealthCheckerFunc) CheckHealth(ctx context.Context) error {
+	return f(ctx)
+}
+
+type HealthCheckConfig struct {
+	// Critical checks fail readiness; others only report the service as degraded.
+	Critical bool
+	// Timeout bounds a single check run. Defaults to 2 seconds.
+	Timeout time.Duration
+	// CacheTTL reuses the last result for the given duration, protecting
+	// dependencies from being probed on every readiness request.
+	CacheTTL time.Duration
+}
+
+type healthCheck struct {
+	name    string
+	checker HealthChecker
+	HealthCheckConfig
+
+	mu        sync.Mutex
+	checkedAt time.Time
+	result    healthResult
+}
+
+type healthResult struct {
+	Status     string `json:"status"`
+	Critical   bool   `json:"critical"`
+	DurationMs int64  `json:"duration_ms"`
+}
+
+type healthReport struct {
+	Status string                  `json:"status"`
+	Checks map[string]healthResult `json:"checks,omitempty"`
+}
+
+type health struct {
+	checks   []*healthCheck
+	draining atomic.Bool
+}
+
+func (h *health) add(name string, checker HealthChecker, cfg HealthCheckConfig) {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultHealthCheckTimeout
+	}
+	h.checks = append(h.checks, &healthCheck{
+		name:              name,
+		checker:           checker,
+		HealthCheckConfig: cfg,
+	})
+}
+
+func (c *healthCheck) run(ctx context.Context) healthResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.CacheTTL > 0 && time.Since(c.checkedAt) < c.CacheTTL {
+		return c.result
+	}
+	ctx, cancel := context.WithTimeout(ctx, c.Timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.checker.CheckHealth(ctx)
+	c.result = healthResult{
+		Status:     "ok",
+		Critical:   c.Critical,
+		DurationMs: time.Since(start).Milliseconds(),
+	}
+	if err != nil {
+		// Errors may name hosts or credentials, so they are logged rather than
+		// served. Probes repeat every few seconds; Sentry would be flooded.
+		c.result.Status = "fail"
+		slog.WarnContext(cmlt.ContextWithoutCapture(ctx), "Health check failed",
+			slog.String("check", c.name),
+			slog.Bool("critical", c.Critical),
+			slog.Any("error", err),
+		)
+	}
+	c.checkedAt = time.Now()
+	return c.result
+}
+
+func (h *health) report(ctx context.Context) (healthReport, bool) {
+	report := healthReport{
+		Status: "ok",
+		Checks: make(map[string]healthResult, len(h.checks)),
+	}
+	results := make([]healthResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = check.run(ctx)
+		}()
+	}
+	wg.Wait()
+
+	ready := true
+	for i, check := range h.checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status == "ok" {
+			continue
+		}
+		if check.Critical {
+			ready = false
+		} else if report.Status == "ok" {
+			report.Status = "degraded"
+		}
+	}
+	if h.draining.Load() {
+		report.Status = "draining"
+		ready = false
+	} else if !ready {
+		report.Status = "fail"
+	}
+	return report, ready
+}
+
+func (h *health) livenessHandler(c fiber.Ctx) error {
+	return c.SendString("OK")
+}
+
+func (h *health) readinessHandler(c fiber.Ctx) error {
+	report, ready := h.report(c.Context())
+	if !ready {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	return c.JSON(report)
+}
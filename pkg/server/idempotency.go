@@ -0,0 +1,239 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/xerrors"
+)
+
+const (
+	idempotencyKeyHeader    = "Idempotency-Key"
+	maxIdempotencyKeyLength = 255
+)
+
+type IdempotencyConfig struct {
+	// Methods defaults to POST and PATCH.
+	Methods []string
+	// TTL keeps completed responses for replay. Defaults to 24 hours.
+	TTL time.Duration
+	// LockTTL releases a key whose request never completed, e.g. after a
+	// crash. Defaults to 1 minute.
+	LockTTL time.Duration
+	// Store defaults to a store in process memory, which only deduplicates
+	// retries that reach the same instance.
+	Store IdempotencyStore
+}
+
+// completeAttempts bounds how often a finished response is offered to the
+// store before it is given up on.
+const completeAttempts = 3
+
+// IdempotencyRecord is the stored state of a key: in flight until Completed,
+// then the response to replay.
+type IdempotencyRecord struct {
+	Fingerprint string              `json:"fingerprint"`
+	Completed   bool                `json:"completed"`
+	Status      int                 `json:"status,omitempty"`
+	Header      map[string][]string `json:"header,omitempty"`
+	Body        []byte              `json:"body,omitempty"`
+}
+
+type IdempotencyStore interface {
+	// Reserve stores record under key unless the key exists, in which case it
+	// returns the existing record and false.
+	Reserve(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error)
+	// Complete replaces the reservation with the final response.
+	Complete(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error
+	// Release drops a reservation so the request may be retried.
+	Release(ctx context.Context, key string) error
+}
+
+// Response headers that describe this delivery rather than the stored result,
+// including those the rate limiter and CORS set afresh on every request.
+var unreplayedHeaders = []string{
+	fiber.HeaderContentLength, fiber.HeaderDate, fiber.HeaderConnection, fiber.HeaderTransferEncoding,
+	fiber.HeaderServer, fiber.HeaderXRequestID, fiber.HeaderRetryAfter, fiber.HeaderVary,
+}
+
+var unreplayedHeaderPrefixes = []string{"RateLimit-", "Access-Control-"}
+
+func replayedHeader(name string) bool {
+	if slices.ContainsFunc(unreplayedHeaders, func(h string) bool { return strings.EqualFold(h, name) }) {
+		return false
+	}
+	return !slices.ContainsFunc(unreplayedHeaderPrefixes, func(prefix string) bool {
+		return len(name) >= len(prefix) && strings.EqualFold(name[:len(prefix)], prefix)
+	})
+}
+
+// Idempotency is route middleware that replays the first response for
+// repeated Idempotency-Key requests; see WithIdempotency to guard every route.
+// Keys are scoped to the token subject when auth runs first; without auth all
+// callers share one key space, so clients must send unguessable keys such as UUIDs.
+func Idempotency(cfg IdempotencyConfig) fiber.Handler {
+	if len(cfg.Methods) == 0 {
+		cfg.Methods = []string{fiber.MethodPost, fiber.MethodPatch}
+	}
+	if cfg.TTL <= 0 {
+		cfg.TTL = 24 * time.Hour
+	}
+	if cfg.LockTTL <= 0 {
+		cfg.LockTTL = time.Minute
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryIdempotencyStore()
+	}
+
+	return func(c fiber.Ctx) error {
+		key := c.Get(idempotencyKeyHeader)
+		if key == "" || !slices.Contains(cfg.Methods, c.Method()) {
+			return c.Next()
+		}
+		if len(key) > maxIdempotencyKeyLength {
+			return NewProblem(fiber.StatusBadRequest, "idempotency key is too long")
+		}
+		ctx := c.Context()
+		// Keys are per caller when a token is present, so one client cannot
+		// replay another's response.
+		if claims, ok := ClaimsFromContext(ctx); ok {
+			key = claims.Subject + ":" + key
+		}
+		key = strings.Clone(key)
+		fingerprint := requestFingerprint(c)
+
+		existing, reserved, err := cfg.Store.Reserve(ctx, key, IdempotencyRecord{Fingerprint: fingerprint}, cfg.LockTTL)
+		if err != nil {
+			return xerrors.WithKind(xerrors.KindUnavailable, err)
+		}
+		if !reserved {
+			switch {
+			case existing.Fingerprint != fingerprint:
+				return NewProblem(fiber.StatusUnprocessableEntity, "idempotency key was used for a different request")
+			case !existing.Completed:
+				return NewProblem(fiber.StatusConflict, "a request with this idempotency key is in progress")
+			}
+			for name, values := range existing.Header {
+				for i, value := range values {
+					if i == 0 {
+						c.Set(name, value)
+					} else {
+						c.Response().Header.Add(name, value)
+					}
+				}
+			}
+			c.Set("Idempotent-Replayed", "true")
+			return c.Status(existing.Status).Send(existing.Body)
+		}
+
+		err = next(c)
+		status := c.Response().StatusCode()
+		// Server errors are not final; the client should be able to retry them.
+		if status >= fiber.StatusInternalServerError {
+			if releaseErr := cfg.Store.Release(context.WithoutCancel(ctx), key); releaseErr != nil {
+				return xerrors.WithKind(xerrors.KindUnavailable, releaseErr)
+			}
+			return err
+		}
+		record := IdempotencyRecord{
+			Fingerprint: fingerprint,
+			Completed:   true,
+			Status:      status,
+			Header:      map[string][]string{},
+			Body:        slices.Clone(c.Response().Body()),
+		}
+		c.Response().Header.VisitAll(func(name, value []byte) {
+			if replayedHeader(string(name)) {
+				record.Header[string(name)] = append(record.Header[string(name)], string(value))
+			}
+		})
+		// The handler's side effect has happened, so its response goes out even
+		// if it cannot be stored; the reservation then holds off retries until
+		// LockTTL instead of letting them run the handler again right away.
+		if err := completeIdempotency(context.WithoutCancel(ctx), cfg, key, record); err != nil {
+			slog.WarnContext(ctx, "Store idempotent response failed", slog.Any("error", err))
+		}
+		return err
+	}
+}
+
+func completeIdempotency(ctx context.Context, cfg IdempotencyConfig, key string, record IdempotencyRecord) error {
+	var err error
+	for attempt := range completeAttempts {
+		if attempt > 0 {
+			time.Sleep(time.Duration(attempt) * 50 * time.Millisecond)
+		}
+		if err = cfg.Store.Complete(ctx, key, record, cfg.TTL); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func requestFingerprint(c fiber.Ctx) string {
+	hash := sha256.New()
+	hash.Write([]byte(c.Method()))
+	hash.Write([]byte{0})
+	hash.Write(c.Request().URI().RequestURI())
+	hash.Write([]byte{0})
+	hash.Write(c.Body())
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+type memoryIdempotencyEntry struct {
+	record  IdempotencyRecord
+	expires time.Time
+}
+
+type memoryIdempotencyStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryIdempotencyEntry
+	lastSweep time.Time
+}
+
+func NewMemoryIdempotencyStore() IdempotencyStore {
+	return &memoryIdempotencyStore{
+		entries:   map[string]memoryIdempotencyEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryIdempotencyStore) Reserve(_ context.Context, key string, record IdempotencyRecord, ttl time.Duration) (*IdempotencyRecord, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	if entry, ok := s.entries[key]; ok && now.Before(entry.expires) {
+		return &entry.record, false, nil
+	}
+	s.entries[key] = memoryIdempotencyEntry{record, now.Add(ttl)}
+	return nil, true, nil
+}
+
+func (s *memoryIdempotencyStore) Complete(_ context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries[key] = memoryIdempotencyEntry{record, time.Now().Add(ttl)}
+	return nil
+}
+
+func (s *memoryIdempotencyStore) Release(_ context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.entries, key)
+	return nil
+}
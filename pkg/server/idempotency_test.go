@@ -0,0 +1,285 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// newIdempotencyApp guards /items/:id, counting handler runs. The X-Subject
+// header stands in for the auth middleware.
+func newIdempotencyApp(t *testing.T, cfg IdempotencyConfig, handler fiber.Handler) (*fiber.App, *atomic.Int32) {
+	t.Helper()
+	s, err := New()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var runs atomic.Int32
+	counted := func(c fiber.Ctx) error {
+		runs.Add(1)
+		return handler(c)
+	}
+	authenticate := func(c fiber.Ctx) error {
+		if subject := c.Get("X-Subject"); subject != "" {
+			c.SetContext(ContextWithClaims(c.Context(), &Claims{RegisteredClaims: jwt.RegisteredClaims{Subject: subject}}))
+		}
+		return c.Next()
+	}
+	app := s.Router()
+	guard := Idempotency(cfg)
+	app.Post("/items/:id", counted, authenticate, guard)
+	app.Get("/items/:id", counted, authenticate, guard)
+	return app, &runs
+}
+
+type idempotencyRequest struct {
+	method  string
+	path    string
+	key     string
+	subject string
+	body    string
+}
+
+func (r idempotencyRequest) send(t *testing.T, app *fiber.App) *http.Response {
+	t.Helper()
+	method, path := r.method, r.path
+	if method == "" {
+		method = http.MethodPost
+	}
+	if path == "" {
+		path = "/items/1"
+	}
+	req := httptest.NewRequest(method, path, strings.NewReader(r.body))
+	req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	if r.key != "" {
+		req.Header.Set(idempotencyKeyHeader, r.key)
+	}
+	if r.subject != "" {
+		req.Header.Set("X-Subject", r.subject)
+	}
+	res, err := app.Test(req, fiber.TestConfig{Timeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { res.Body.Close() })
+	return res
+}
+
+func created(c fiber.Ctx) error {
+	if string(c.Body()) == `"fail"` {
+		return errors.New("boom")
+	}
+	return c.Status(fiber.StatusCreated).Send(c.Body())
+}
+
+func TestIdempotency(t *testing.T) {
+	first := idempotencyRequest{key: "k1", body: `{"n":1}`}
+	type step struct {
+		req          idempotencyRequest
+		wantStatus   int
+		wantReplayed bool
+	}
+	tests := []struct {
+		name     string
+		steps    []step
+		wantRuns int32
+	}{
+		{
+			name: "replays completed response",
+			steps: []step{
+				{first, fiber.StatusCreated, false},
+				{first, fiber.StatusCreated, true},
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "rejects another body under the key",
+			steps: []step{
+				{first, fiber.StatusCreated, false},
+				{idempotencyRequest{key: "k1", body: `{"n":2}`}, fiber.StatusUnprocessableEntity, false},
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "rejects another path under the key",
+			steps: []step{
+				{first, fiber.StatusCreated, false},
+				{idempotencyRequest{key: "k1", path: "/items/2", body: `{"n":1}`}, fiber.StatusUnprocessableEntity, false},
+			},
+			wantRuns: 1,
+		},
+		{
+			name: "runs requests without a key",
+			steps: []step{
+				{idempotencyRequest{body: `{"n":1}`}, fiber.StatusCreated, false},
+				{idempotencyRequest{body: `{"n":1}`}, fiber.StatusCreated, false},
+			},
+			wantRuns: 2,
+		},
+		{
+			name: "ignores unguarded methods",
+			steps: []step{
+				{idempotencyRequest{method: http.MethodGet, key: "k1"}, fiber.StatusOK, false},
+				{idempotencyRequest{method: http.MethodGet, key: "k1"}, fiber.StatusOK, false},
+			},
+			wantRuns: 2,
+		},
+		{
+			name: "rejects long keys",
+			steps: []step{
+				{idempotencyRequest{key: strings.Repeat("k", maxIdempotencyKeyLength+1)}, fiber.StatusBadRequest, false},
+			},
+			wantRuns: 0,
+		},
+		{
+			name: "releases keys of server errors",
+			steps: []step{
+				{idempotencyRequest{key: "k1", body: `"fail"`}, fiber.StatusInternalServerError, false},
+				{idempotencyRequest{key: "k1", body: `"fail"`}, fiber.StatusInternalServerError, false},
+			},
+			wantRuns: 2,
+		},
+		{
+			name: "scopes keys to the token subject",
+			steps: []step{
+				{idempotencyRequest{key: "k1", subject: "alice", body: `{"n":1}`}, fiber.StatusCreated, false},
+				{idempotencyRequest{key: "k1", subject: "bob", body: `{"n":2}`}, fiber.StatusCreated, false},
+				{idempotencyRequest{key: "k1", subject: "alice", body: `{"n":1}`}, fiber.StatusCreated, true},
+			},
+			wantRuns: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app, runs := newIdempotencyApp(t, IdempotencyConfig{}, func(c fiber.Ctx) error {
+				if c.Method() == fiber.MethodGet {
+					return c.SendStatus(fiber.StatusOK)
+				}
+				return created(c)
+			})
+			for i, step := range tt.steps {
+				res := step.req.send(t, app)
+				if res.StatusCode != step.wantStatus {
+					t.Errorf("step %d: status = %d, want %d", i, res.StatusCode, step.wantStatus)
+				}
+				if replayed := res.Header.Get("Idempotent-Replayed") == "true"; replayed != step.wantReplayed {
+					t.Errorf("step %d: replayed = %t, want %t", i, replayed, step.wantReplayed)
+				}
+			}
+			if n := runs.Load(); n != tt.wantRuns {
+				t.Errorf("handler runs = %d, want %d", n, tt.wantRuns)
+			}
+		})
+	}
+}
+
+func TestIdempotencyReplayHeaders(t *testing.T) {
+	var calls atomic.Int32
+	app, _ := newIdempotencyApp(t, IdempotencyConfig{}, func(c fiber.Ctx) error {
+		n := calls.Add(1)
+		c.Set("X-Order", "o-1")
+		if n == 1 {
+			c.Set("RateLimit-Remaining", "9")
+			c.Set(fiber.HeaderAccessControlAllowOrigin, "https://app.example")
+			c.Set(fiber.HeaderVary, fiber.HeaderOrigin)
+			c.Set(fiber.HeaderRetryAfter, "1")
+		}
+		return created(c)
+	})
+	req := idempotencyRequest{key: "k1", body: `{"n":1}`}
+	req.send(t, app)
+	res := req.send(t, app)
+
+	body, _ := io.ReadAll(res.Body)
+	if string(body) != `{"n":1}` {
+		t.Errorf("body = %q, want the stored body", body)
+	}
+	if got := res.Header.Get("X-Order"); got != "o-1" {
+		t.Errorf("X-Order = %q, want the stored header", got)
+	}
+	for _, name := range []string{"RateLimit-Remaining", fiber.HeaderAccessControlAllowOrigin, fiber.HeaderVary, fiber.HeaderRetryAfter} {
+		if got := res.Header.Get(name); got != "" {
+			t.Errorf("%s = %q was replayed", name, got)
+		}
+	}
+}
+
+func TestIdempotencyInProgress(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	app, runs := newIdempotencyApp(t, IdempotencyConfig{}, func(c fiber.Ctx) error {
+		close(entered)
+		<-release
+		return created(c)
+	})
+	req := idempotencyRequest{key: "k1", body: `{"n":1}`}
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		if res := req.send(t, app); res.StatusCode != fiber.StatusCreated {
+			t.Errorf("first status = %d, want %d", res.StatusCode, fiber.StatusCreated)
+		}
+	}()
+	<-entered
+	if res := req.send(t, app); res.StatusCode != fiber.StatusConflict {
+		t.Errorf("concurrent status = %d, want %d", res.StatusCode, fiber.StatusConflict)
+	}
+	close(release)
+	wg.Wait()
+	if n := runs.Load(); n != 1 {
+		t.Errorf("handler runs = %d, want 1", n)
+	}
+}
+
+// flakyIdempotencyStore fails the first failures Complete calls.
+type flakyIdempotencyStore struct {
+	IdempotencyStore
+	failures  int32
+	completes atomic.Int32
+}
+
+func (s *flakyIdempotencyStore) Complete(ctx context.Context, key string, record IdempotencyRecord, ttl time.Duration) error {
+	if s.completes.Add(1) <= s.failures {
+		return errors.New("store unavailable")
+	}
+	return s.IdempotencyStore.Complete(ctx, key, record, ttl)
+}
+
+func TestIdempotencyCompleteFailures(t *testing.T) {
+	tests := []struct {
+		name       string
+		failures   int32
+		wantStatus int
+	}{
+		{"retried until stored", completeAttempts - 1, fiber.StatusCreated},
+		{"keeps the lock when never stored", completeAttempts, fiber.StatusConflict},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &flakyIdempotencyStore{IdempotencyStore: NewMemoryIdempotencyStore(), failures: tt.failures}
+			app, runs := newIdempotencyApp(t, IdempotencyConfig{Store: store}, created)
+			req := idempotencyRequest{key: "k1", body: `{"n":1}`}
+
+			if res := req.send(t, app); res.StatusCode != fiber.StatusCreated {
+				t.Fatalf("first status = %d, want the handler's response", res.StatusCode)
+			}
+			if res := req.send(t, app); res.StatusCode != tt.wantStatus {
+				t.Errorf("retry status = %d, want %d", res.StatusCode, tt.wantStatus)
+			}
+			if n := runs.Load(); n != 1 {
+				t.Errorf("handler runs = %d, want 1", n)
+			}
+		})
+	}
+}
@@ -0,0 +1,246 @@
+package server
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"golang.org/x/sync/singleflight"
+)
+
+// minJWKSRefreshInterval bounds refetches triggered by unknown key IDs,
+// so tokens with made-up kids cannot hammer the identity provider.
+const minJWKSRefreshInterval = 10 * time.Second
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+type jwks struct {
+	url             string
+	httpClient      *http.Client
+	refreshInterval time.Duration
+
+	mu        sync.RWMutex
+	keys      map[string]crypto.PublicKey
+	fetchedAt time.Time
+	fetches   singleflight.Group
+}
+
+func newJWKS(url string, httpClient *http.Client, refreshInterval time.Duration) *jwks {
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &jwks{
+		url:             url,
+		httpClient:      httpClient,
+		refreshInterval: refreshInterval,
+		keys:            map[string]crypto.PublicKey{},
+	}
+}
+
+// watch fetches the key set immediately and then on every refresh interval
+// until ctx is done.
+func (s *jwks) watch(ctx context.Context) {
+	ticker := time.NewTicker(s.refreshInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.refresh(ctx, 0); err != nil && ctx.Err() == nil {
+			slog.WarnContext(ctx, "Failed to refresh JWKS", slog.String("url", s.url), slog.Any("error", err))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// key returns the key for kid, refetching the set once if it is unknown so
+// that keys rotated in by the identity provider are picked up immediately.
+// An empty kid yields every key in the set.
+func (s *jwks) key(ctx context.Context, kid string) (any, error) {
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	if err := s.refresh(ctx, minJWKSRefreshInterval); err != nil {
+		return nil, err
+	}
+	if key, ok := s.lookup(kid); ok {
+		return key, nil
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+func (s *jwks) lookup(kid string) (any, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if kid != "" {
+		key, ok := s.keys[kid]
+		return key, ok
+	}
+	if len(s.keys) == 0 {
+		return nil, false
+	}
+	set := jwt.VerificationKeySet{}
+	for _, key := range s.keys {
+		set.Keys = append(set.Keys, key)
+	}
+	return set, true
+}
+
+// refresh refetches the set unless that happened within minInterval. Callers
+// arriving during a fetch share its result instead of queueing behind it.
+func (s *jwks) refresh(ctx context.Context, minInterval time.Duration) error {
+	s.mu.RLock()
+	recent := time.Since(s.fetchedAt) < minInterval
+	s.mu.RUnlock()
+	if recent {
+		return nil
+	}
+	result := s.fetches.DoChan("", func() (any, error) {
+		// The fetch is shared, so one caller giving up must not cancel it for the rest.
+		keys, err := s.fetch(context.WithoutCancel(ctx))
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.fetchedAt = time.Now()
+		if err != nil {
+			return nil, err
+		}
+		s.keys = keys
+		return nil, nil
+	})
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case r := <-result:
+		return r.Err
+	}
+}
+
+func (s *jwks) fetch(ctx context.Context) (map[string]crypto.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("fetch jwks: %w", err)
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("fetch jwks: unexpected status %d", res.StatusCode)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&set); err != nil {
+		return nil, fmt.Errorf("decode jwks: %w", err)
+	}
+	keys := map[string]crypto.PublicKey{}
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			slog.WarnContext(ctx, "Skipped invalid JWK", slog.String("kid", k.Kid), slog.Any("error", err))
+			continue
+		}
+		keys[k.Kid] = key
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("jwks has no usable signing keys")
+	}
+	return keys, nil
+}
+
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, err
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, err
+		}
+		if !e.IsInt64() || e.Int64() > 1<<31-1 {
+			return nil, errors.New("rsa exponent out of range")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		var ecdhCurve ecdh.Curve
+		switch k.Crv {
+		case "P-256":
+			curve, ecdhCurve = elliptic.P256(), ecdh.P256()
+		case "P-384":
+			curve, ecdhCurve = elliptic.P384(), ecdh.P384()
+		case "P-521":
+			curve, ecdhCurve = elliptic.P521(), ecdh.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		y, err := base64.RawURLEncoding.DecodeString(k.Y)
+		if err != nil {
+			return nil, err
+		}
+		size := (curve.Params().BitSize + 7) / 8
+		if len(x) != size || len(y) != size {
+			return nil, errors.New("invalid ec point size")
+		}
+		// ecdh rejects points that are not on the curve.
+		if _, err := ecdhCurve.NewPublicKey(append(append([]byte{4}, x...), y...)); err != nil {
+			return nil, err
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil {
+			return nil, err
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.New("invalid ed25519 key size")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, fmt.Errorf("unsupported key type %q", k.Kty)
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,113 @@
+package server
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/gofiber/fiber/v3/middleware/adaptor"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// MetricsConfig configures the request metrics, labelled by route template.
+// Requests that match no route are labelled "unmatched", and those the
+// server's middleware rejects before routing, e.g. auth or rate limits,
+// "rejected".
+type MetricsConfig struct {
+	// Path serves the OpenMetrics exposition. Defaults to /metrics.
+	Path string
+	// Registry receives the built-in collectors and may carry service metrics.
+	// A new registry with Go and process collectors is used when nil.
+	Registry *prometheus.Registry
+	// DurationBuckets overrides the request duration histogram buckets, in seconds.
+	DurationBuckets []float64
+}
+
+type metrics struct {
+	path     string
+	registry *prometheus.Registry
+
+	requests     *prometheus.CounterVec
+	duration     *prometheus.HistogramVec
+	inFlight     prometheus.Gauge
+	responseSize *prometheus.HistogramVec
+}
+
+func newMetrics(cfg MetricsConfig) (*metrics, error) {
+	if cfg.Path == "" {
+		cfg.Path = "/metrics"
+	}
+	if cfg.Registry == nil {
+		cfg.Registry = prometheus.NewRegistry()
+		cfg.Registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	}
+	if len(cfg.DurationBuckets) == 0 {
+		cfg.DurationBuckets = prometheus.DefBuckets
+	}
+	labels := []string{"method", "route", "status"}
+	m := &metrics{
+		path:     cfg.Path,
+		registry: cfg.Registry,
+		requests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "http_server_requests_total",
+			Help: "Total number of HTTP requests handled.",
+		}, labels),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_request_duration_seconds",
+			Help:    "Duration of HTTP requests.",
+			Buckets: cfg.DurationBuckets,
+		}, labels),
+		inFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "http_server_requests_in_flight",
+			Help: "Number of HTTP requests currently being handled.",
+		}),
+		responseSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "http_server_response_size_bytes",
+			Help:    "Size of HTTP response bodies.",
+			Buckets: prometheus.ExponentialBuckets(128, 4, 8),
+		}, labels),
+	}
+	for _, collector := range []prometheus.Collector{m.requests, m.duration, m.inFlight, m.responseSize} {
+		if err := m.registry.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *metrics) handler() fiber.Handler {
+	return adaptor.HTTPHandler(promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{
+		EnableOpenMetrics: true,
+	}))
+}
+
+func (m *metrics) middleware(c fiber.Ctx) error {
+	m.inFlight.Inc()
+	defer m.inFlight.Dec()
+	start := time.Now()
+	err := next(c)
+	elapsed := time.Since(start).Seconds()
+
+	route := routeLabel(c)
+	labels := prometheus.Labels{
+		"method": strings.Clone(c.Method()),
+		"route":  route,
+		"status": strconv.Itoa(c.Response().StatusCode()),
+	}
+	m.requests.With(labels).Inc()
+	m.responseSize.With(labels).Observe(float64(len(c.Response().Body())))
+
+	duration := m.duration.With(labels)
+	if spanContext := trace.SpanContextFromContext(c.Context()); spanContext.IsSampled() {
+		duration.(prometheus.ExemplarObserver).ObserveWithExemplar(elapsed, prometheus.Labels{
+			"trace_id": spanContext.TraceID().String(),
+		})
+	} else {
+		duration.Observe(elapsed)
+	}
+	return err
+}
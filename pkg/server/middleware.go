@@ -0,0 +1,111 @@
+package server
+
+import (
+	"sync"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type localsKey string
+
+var handlerErrorKey localsKey = "handler_error"
+
+// next runs the rest of the chain and renders a returned error through the app's
+// error handler right away, so the calling middleware observes the final response.
+// The original error stays available to outer middleware via handlerError.
+func next(c fiber.Ctx) error {
+	err := c.Next()
+	if err == nil {
+		return nil
+	}
+	if handlerError(c) == nil {
+		c.Locals(handlerErrorKey, err)
+	}
+	if err := c.App().ErrorHandler(c, err); err != nil {
+		_ = c.SendStatus(fiber.StatusInternalServerError)
+	}
+	return nil
+}
+
+func handlerError(c fiber.Ctx) error {
+	err, _ := c.Locals(handlerErrorKey).(error)
+	return err
+}
+
+// Labels of requests that no route handled.
+const (
+	routeUnmatched = "unmatched"
+	routeRejected  = "rejected"
+)
+
+var routesKey localsKey = "routes"
+
+// routes tells the routes registered with Use apart from the endpoints they
+// guard; fiber keeps that flag unexported. Endpoints are found through
+// App.GetRoutes, whose copies share handler slices with the routes in the
+// stack, and are looked up again whenever handlers are added.
+type routes struct {
+	mu        sync.RWMutex
+	handlers  uint32
+	endpoints map[*fiber.Handler]struct{}
+}
+
+// middleware runs after the server's own middleware, so requests that never
+// reach it were rejected before routing.
+func (r *routes) middleware(c fiber.Ctx) error {
+	c.Locals(routesKey, r)
+	return c.Next()
+}
+
+func (r *routes) endpoint(c fiber.Ctx) bool {
+	route := c.Route()
+	if len(route.Handlers) == 0 {
+		return false
+	}
+	handlers := c.App().HandlersCount()
+	r.mu.RLock()
+	if r.endpoints != nil && r.handlers == handlers {
+		_, ok := r.endpoints[&route.Handlers[0]]
+		r.mu.RUnlock()
+		return ok
+	}
+	r.mu.RUnlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.endpoints == nil || r.handlers != handlers {
+		r.endpoints = make(map[*fiber.Handler]struct{})
+		for _, endpoint := range c.App().GetRoutes(true) {
+			if len(endpoint.Handlers) > 0 {
+				r.endpoints[&endpoint.Handlers[0]] = struct{}{}
+			}
+		}
+		r.handlers = handlers
+	}
+	_, ok := r.endpoints[&route.Handlers[0]]
+	return ok
+}
+
+// routeTemplate returns the registered path of the route that handled the
+// request, and false when the request matched no route or middleware
+// rejected it first.
+func routeTemplate(c fiber.Ctx) (string, bool) {
+	r, ok := c.Locals(routesKey).(*routes)
+	if !ok || !r.endpoint(c) {
+		return "", false
+	}
+	return c.Route().Path, true
+}
+
+// routeLabel is routeTemplate for logs and metrics: requests no route handled
+// are labelled routeRejected when the server's middleware turned them away
+// and routeUnmatched otherwise.
+func routeLabel(c fiber.Ctx) string {
+	if route, ok := routeTemplate(c); ok {
+		return route
+	}
+	if _, ok := c.Locals(routesKey).(*routes); !ok {
+		return routeRejected
+	}
+	return routeUnmatched
+}
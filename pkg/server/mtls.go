@@ -0,0 +1,106 @@
+package server
+
+import (
+	"context"
+	"crypto/x509"
+	"fmt"
+	"path"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// PeerIdentity describes the verified client certificate of an mTLS request.
+type PeerIdentity struct {
+	Subject        string
+	CommonName     string
+	DNSNames       []string
+	URIs           []string
+	EmailAddresses []string
+	IPAddresses    []string
+	// SPIFFEID is the certificate's spiffe:// URI SAN, if any.
+	SPIFFEID string
+}
+
+func newPeerIdentity(cert *x509.Certificate) *PeerIdentity {
+	identity := &PeerIdentity{
+		Subject:        cert.Subject.String(),
+		CommonName:     cert.Subject.CommonName,
+		DNSNames:       cert.DNSNames,
+		EmailAddresses: cert.EmailAddresses,
+	}
+	for _, uri := range cert.URIs {
+		identity.URIs = append(identity.URIs, uri.String())
+		if uri.Scheme == "spiffe" && identity.SPIFFEID == "" {
+			identity.SPIFFEID = uri.String()
+		}
+	}
+	for _, ip := range cert.IPAddresses {
+		identity.IPAddresses = append(identity.IPAddresses, ip.String())
+	}
+	return identity
+}
+
+// names are the identities matched by RequirePeer.
+func (p *PeerIdentity) names() []string {
+	names := append([]string{}, p.URIs...)
+	return append(names, p.DNSNames...)
+}
+
+type peerIdentityContextKey struct{}
+
+// ContextWithPeerIdentity is used by the server for mTLS requests; tests may
+// call it to run handlers as a given peer without a TLS connection.
+func ContextWithPeerIdentity(ctx context.Context, identity *PeerIdentity) context.Context {
+	return context.WithValue(ctx, peerIdentityContextKey{}, identity)
+}
+
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	identity, ok := ctx.Value(peerIdentityContextKey{}).(*PeerIdentity)
+	return identity, ok
+}
+
+// peerIdentityMiddleware exposes the leaf of the first verified chain;
+// unverified certificates are never trusted as an identity.
+func peerIdentityMiddleware(c fiber.Ctx) error {
+	state := c.RequestCtx().TLSConnectionState()
+	if state == nil || len(state.VerifiedChains) == 0 || len(state.VerifiedChains[0]) == 0 {
+		return c.Next()
+	}
+	identity := newPeerIdentity(state.VerifiedChains[0][0])
+	ctx := ContextWithPeerIdentity(c.Context(), identity)
+	attrs := []attribute.KeyValue{attribute.String("tls.client.subject", identity.Subject)}
+	if identity.SPIFFEID != "" {
+		attrs = append(attrs, attribute.String("tls.client.spiffe_id", identity.SPIFFEID))
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attrs...)
+	c.SetContext(ctx)
+	return c.Next()
+}
+
+// RequirePeer allows a request only if the client certificate has a URI SAN
+// (including its SPIFFE ID) or DNS SAN matching one of the path.Match patterns,
+// e.g. "spiffe://prod.example.org/ns/payments/sa/*" or "*.payments.svc".
+// It is route middleware and panics on a malformed pattern.
+func RequirePeer(patterns ...string) fiber.Handler {
+	for _, pattern := range patterns {
+		if _, err := path.Match(pattern, ""); err != nil {
+			panic(fmt.Sprintf("server: invalid peer pattern %q: %s", pattern, err))
+		}
+	}
+	return func(c fiber.Ctx) error {
+		identity, ok := PeerIdentityFromContext(c.Context())
+		if !ok {
+			return NewProblem(fiber.StatusUnauthorized, "missing client certificate")
+		}
+		for _, name := range identity.names() {
+			for _, pattern := range patterns {
+				if matched, _ := path.Match(pattern, name); matched {
+					return c.Next()
+				}
+			}
+		}
+		return NewProblem(fiber.StatusForbidden, "peer is not allowed")
+	}
+}
@@ -0,0 +1,785 @@
+package server
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"path"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/env"
+	"github.com/valyala/fasthttp"
+)
+
+var swaggerUIAssets = map[string]string{
+	"swagger-ui-bundle.js": fiber.MIMEApplicationJavaScriptCharsetUTF8,
+	"swagger-ui.css":       "text/css; charset=utf-8",
+	"init.js":              fiber.MIMEApplicationJavaScriptCharsetUTF8,
+}
+
+type OpenAPIConfig struct {
+	// Path serves the OpenAPI 3.1 document as JSON. Defaults to /openapi.json.
+	Path string
+	// UI holds the Swagger UI assets, usually swaggerui.FS. When set, UIPath
+	// serves the page for the document outside production. Defaults to /docs.
+	UI     fs.FS
+	UIPath string
+	// Title and Version fill the info object. They default to "API" and "0.0.0".
+	Title       string
+	Version     string
+	Description string
+	// Servers are the base URLs listed in the document.
+	Servers []string
+}
+
+// DocOption annotates the OpenAPI operation of a typed handler.
+type DocOption func(doc *operationDoc)
+
+func DocSummary(summary string) DocOption {
+	return func(doc *operationDoc) {
+		doc.summary = summary
+	}
+}
+
+func DocDescription(description string) DocOption {
+	return func(doc *operationDoc) {
+		doc.description = description
+	}
+}
+
+func DocTags(tags ...string) DocOption {
+	return func(doc *operationDoc) {
+		doc.tags = append(doc.tags, tags...)
+	}
+}
+
+// DocOperationID overrides the operation ID derived from the method and path.
+func DocOperationID(id string) DocOption {
+	return func(doc *operationDoc) {
+		doc.operationID = id
+	}
+}
+
+func DocDeprecated() DocOption {
+	return func(doc *operationDoc) {
+		doc.deprecated = true
+	}
+}
+
+type operationDoc struct {
+	request     reflect.Type
+	response    reflect.Type
+	summary     string
+	description string
+	tags        []string
+	operationID string
+	deprecated  bool
+}
+
+type documenter func(op *openAPIOperation, schemas *schemaRegistry)
+
+// describeKey marks the context of a probe run while generating the document,
+// in which handlers from Handle, RequireScopes and RequireRole report the part
+// of the operation they describe instead of serving a request.
+var describeKey localsKey = "openapi_describe"
+
+// documentedCode holds the code pointers of those handlers so that no other
+// handler is ever probed. It grows with closure sites and generic shapes, not
+// with the number of handlers or servers.
+var documentedCode sync.Map
+
+func markDocumented(h fiber.Handler) fiber.Handler {
+	documentedCode.Store(reflect.ValueOf(h).Pointer(), struct{}{})
+	return h
+}
+
+// describe hands fn to a probe and reports whether c is one; documented
+// handlers return right away when it is.
+func describe(c fiber.Ctx, fn documenter) bool {
+	probe, ok := c.Locals(describeKey).(*[]documenter)
+	if ok {
+		*probe = append(*probe, fn)
+	}
+	return ok
+}
+
+func describeHandler(app *fiber.App, h fiber.Handler) []documenter {
+	if _, ok := documentedCode.Load(reflect.ValueOf(h).Pointer()); !ok {
+		return nil
+	}
+	c := app.AcquireCtx(&fasthttp.RequestCtx{})
+	defer app.ReleaseCtx(c)
+	var fns []documenter
+	c.Locals(describeKey, &fns)
+	_ = h(c)
+	return fns
+}
+
+func (d *operationDoc) document(op *openAPIOperation, schemas *schemaRegistry) {
+	op.Summary = d.summary
+	op.Description = d.description
+	op.Tags = append(op.Tags, d.tags...)
+	op.Deprecated = d.deprecated
+	if d.operationID != "" {
+		op.OperationID = d.operationID
+	}
+
+	request := d.request
+	for request.Kind() == reflect.Pointer {
+		request = request.Elem()
+	}
+	hasInput, validated := false, false
+	if request.Kind() == reflect.Struct {
+		var body []reflect.StructField
+		for _, field := range structFields(request) {
+			if field.Tag.Get("validate") != "" {
+				validated = true
+			}
+			param, ok := fieldParameter(field, schemas)
+			if !ok {
+				if jsonName(field) != "-" {
+					body = append(body, field)
+				}
+				continue
+			}
+			hasInput = true
+			if i := slices.IndexFunc(op.Parameters, func(p *openAPIParameter) bool { return p.In == param.In && p.Name == param.Name }); i >= 0 {
+				op.Parameters[i] = param
+			} else {
+				op.Parameters = append(op.Parameters, param)
+			}
+		}
+		switch {
+		case len(body) == 0:
+		case hasInput:
+			op.RequestBody = jsonRequestBody(schemas.objectSchema(body))
+		default:
+			op.RequestBody = jsonRequestBody(schemas.schema(d.request))
+		}
+		hasInput = hasInput || len(body) > 0
+	} else {
+		hasInput = true
+		body := jsonRequestBody(schemas.schema(d.request))
+		body.Required = false
+		op.RequestBody = body
+	}
+
+	status := responseStatus(d.response)
+	response := &openAPIResponse{Description: http.StatusText(status)}
+	if status != fiber.StatusNoContent {
+		response.Content = map[string]openAPIMediaType{
+			fiber.MIMEApplicationJSON: {Schema: schemas.schema(d.response)},
+		}
+	}
+	op.Responses[strconv.Itoa(status)] = response
+	if hasInput {
+		op.Responses["400"] = problemResponse(schemas, "Malformed request")
+	}
+	if validated {
+		op.Responses["422"] = problemResponse(schemas, "Request validation failed")
+	}
+}
+
+// responseStatus asks the zero response for its StatusCoder status.
+func responseStatus(t reflect.Type) (status int) {
+	status = fiber.StatusOK
+	defer func() {
+		if recover() != nil {
+			status = fiber.StatusOK
+		}
+	}()
+	v := reflect.New(t).Elem()
+	if t.Kind() == reflect.Pointer {
+		v = reflect.New(t.Elem())
+	}
+	if coder, ok := v.Interface().(StatusCoder); ok {
+		status = coder.StatusCode()
+	}
+	return status
+}
+
+func fieldParameter(field reflect.StructField, schemas *schemaRegistry) (*openAPIParameter, bool) {
+	for _, tag := range []string{"uri", "query", "header"} {
+		name, _, _ := strings.Cut(field.Tag.Get(tag), ",")
+		if name == "" || name == "-" {
+			continue
+		}
+		param := &openAPIParameter{
+			Name:        name,
+			In:          map[string]string{"uri": "path", "query": "query", "header": "header"}[tag],
+			Description: field.Tag.Get("doc"),
+			Schema:      schemas.fieldSchema(field),
+		}
+		param.Required = param.In == "path" || slices.Contains(validationRules(field), "required")
+		if param.Schema.Examples != nil {
+			param.Example = param.Schema.Examples[0]
+		}
+		// The parameter carries the field docs itself.
+		param.Schema.Description, param.Schema.Examples = "", nil
+		return param, true
+	}
+	return nil, false
+}
+
+func jsonRequestBody(schema *jsonSchema) *openAPIRequestBody {
+	return &openAPIRequestBody{
+		Required: true,
+		Content: map[string]openAPIMediaType{
+			fiber.MIMEApplicationJSON: {Schema: schema},
+		},
+	}
+}
+
+func problemResponse(schemas *schemaRegistry, description string) *openAPIResponse {
+	return &openAPIResponse{
+		Description: description,
+		Content: map[string]openAPIMediaType{
+			problemContentType: {Schema: schemas.schema(reflect.TypeFor[Problem]())},
+		},
+	}
+}
+
+func documentSecurity(scopes []string) documenter {
+	return func(op *openAPIOperation, schemas *schemaRegistry) {
+		op.Security = append(op.Security, map[string][]string{bearerSecurityScheme: scopes})
+		op.Responses["401"] = problemResponse(schemas, "Missing or invalid bearer token")
+		op.Responses["403"] = problemResponse(schemas, "Insufficient scope or role")
+	}
+}
+
+const bearerSecurityScheme = "bearerAuth"
+
+type openAPIDocument struct {
+	OpenAPI    string                                  `json:"openapi"`
+	Info       openAPIInfo                             `json:"info"`
+	Servers    []openAPIServer                         `json:"servers,omitempty"`
+	Paths      map[string]map[string]*openAPIOperation `json:"paths"`
+	Components openAPIComponents                       `json:"components,omitzero"`
+	Security   []map[string][]string                   `json:"security,omitempty"`
+}
+
+type openAPIInfo struct {
+	Title       string `json:"title"`
+	Version     string `json:"version"`
+	Description string `json:"description,omitempty"`
+}
+
+type openAPIServer struct {
+	URL string `json:"url"`
+}
+
+type openAPIComponents struct {
+	Schemas         map[string]*jsonSchema           `json:"schemas,omitempty"`
+	SecuritySchemes map[string]openAPISecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+type openAPISecurityScheme struct {
+	Type         string `json:"type"`
+	Scheme       string `json:"scheme"`
+	BearerFormat string `json:"bearerFormat,omitempty"`
+}
+
+type openAPIOperation struct {
+	OperationID string                      `json:"operationId,omitempty"`
+	Summary     string                      `json:"summary,omitempty"`
+	Description string                      `json:"description,omitempty"`
+	Tags        []string                    `json:"tags,omitempty"`
+	Parameters  []*openAPIParameter         `json:"parameters,omitempty"`
+	RequestBody *openAPIRequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*openAPIResponse `json:"responses"`
+	Deprecated  bool                        `json:"deprecated,omitempty"`
+	// Security is kept when empty to opt an operation out of the document-wide requirement.
+	Security []map[string][]string `json:"security,omitzero"`
+}
+
+type openAPIParameter struct {
+	Name        string      `json:"name"`
+	In          string      `json:"in"`
+	Description string      `json:"description,omitempty"`
+	Required    bool        `json:"required,omitempty"`
+	Schema      *jsonSchema `json:"schema"`
+	Example     any         `json:"example,omitempty"`
+}
+
+type openAPIRequestBody struct {
+	Required bool                        `json:"required,omitempty"`
+	Content  map[string]openAPIMediaType `json:"content"`
+}
+
+type openAPIResponse struct {
+	Description string                      `json:"description"`
+	Content     map[string]openAPIMediaType `json:"content,omitempty"`
+}
+
+type openAPIMediaType struct {
+	Schema *jsonSchema `json:"schema"`
+}
+
+type jsonSchema struct {
+	Ref                  string                 `json:"$ref,omitempty"`
+	Type                 string                 `json:"type,omitempty"`
+	Format               string                 `json:"format,omitempty"`
+	Description          string                 `json:"description,omitempty"`
+	Properties           map[string]*jsonSchema `json:"properties,omitempty"`
+	Required             []string               `json:"required,omitempty"`
+	Items                *jsonSchema            `json:"items,omitempty"`
+	AdditionalProperties *jsonSchema            `json:"additionalProperties,omitempty"`
+	Enum                 []any                  `json:"enum,omitempty"`
+	Minimum              *float64               `json:"minimum,omitempty"`
+	Maximum              *float64               `json:"maximum,omitempty"`
+	ExclusiveMinimum     *float64               `json:"exclusiveMinimum,omitempty"`
+	ExclusiveMaximum     *float64               `json:"exclusiveMaximum,omitempty"`
+	MinLength            *int                   `json:"minLength,omitempty"`
+	MaxLength            *int                   `json:"maxLength,omitempty"`
+	MinItems             *int                   `json:"minItems,omitempty"`
+	MaxItems             *int                   `json:"maxItems,omitempty"`
+	Examples             []any                  `json:"examples,omitempty"`
+}
+
+// schemaRegistry turns Go types into JSON schemas, collecting named structs
+// as components so recursive and shared types are emitted once.
+type schemaRegistry struct {
+	schemas map[string]*jsonSchema
+	names   map[reflect.Type]string
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{
+		schemas: map[string]*jsonSchema{},
+		names:   map[reflect.Type]string{},
+	}
+}
+
+var (
+	timeType          = reflect.TypeFor[time.Time]()
+	rawMessageType    = reflect.TypeFor[json.RawMessage]()
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+func (r *schemaRegistry) schema(t reflect.Type) *jsonSchema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	switch {
+	case t == timeType:
+		return &jsonSchema{Type: "string", Format: "date-time"}
+	case t == rawMessageType:
+		return &jsonSchema{}
+	case t.Implements(jsonMarshalerType) || reflect.PointerTo(t).Implements(jsonMarshalerType):
+		return &jsonSchema{}
+	case t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType):
+		return &jsonSchema{Type: "string"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &jsonSchema{Type: "boolean"}
+	case reflect.Int, reflect.Int64:
+		return &jsonSchema{Type: "integer", Format: "int64"}
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		return &jsonSchema{Type: "integer", Format: "int32"}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &jsonSchema{Type: "integer", Minimum: new(float64)}
+	case reflect.Float32:
+		return &jsonSchema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &jsonSchema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &jsonSchema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 && t.Kind() == reflect.Slice {
+			return &jsonSchema{Type: "string", Format: "byte"}
+		}
+		return &jsonSchema{Type: "array", Items: r.schema(t.Elem())}
+	case reflect.Map:
+		return &jsonSchema{Type: "object", AdditionalProperties: r.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return r.objectSchema(structFields(t))
+		}
+		name, ok := r.names[t]
+		if !ok {
+			name = r.componentName(t)
+			r.names[t] = name
+			// Reserve the name before walking the fields so recursive types resolve to the ref.
+			r.schemas[name] = &jsonSchema{}
+			*r.schemas[name] = *r.objectSchema(structFields(t))
+		}
+		return &jsonSchema{Ref: "#/components/schemas/" + name}
+	}
+	return &jsonSchema{}
+}
+
+func (r *schemaRegistry) componentName(t reflect.Type) string {
+	name := t.Name()
+	if base, args, ok := strings.Cut(name, "["); ok {
+		// Generic instantiations carry full package paths, e.g. Page[example.com/pkg.Order].
+		var parts []string
+		for _, arg := range strings.Split(strings.TrimSuffix(args, "]"), ",") {
+			parts = append(parts, arg[strings.LastIndex(arg, ".")+1:])
+		}
+		name = base + "_" + strings.Join(parts, "_")
+	}
+	if _, taken := r.schemas[name]; taken {
+		name = path.Base(t.PkgPath()) + "." + name
+	}
+	return name
+}
+
+func (r *schemaRegistry) objectSchema(fields []reflect.StructField) *jsonSchema {
+	schema := &jsonSchema{Type: "object", Properties: map[string]*jsonSchema{}}
+	for _, field := range fields {
+		name := jsonName(field)
+		if name == "-" {
+			continue
+		}
+		schema.Properties[name] = r.fieldSchema(field)
+		if slices.Contains(validationRules(field), "required") {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+	return schema
+}
+
+func (r *schemaRegistry) fieldSchema(field reflect.StructField) *jsonSchema {
+	schema := r.schema(field.Type)
+	schema.Description = field.Tag.Get("doc")
+	if example, ok := field.Tag.Lookup("example"); ok {
+		schema.Examples = []any{example}
+	}
+	if schema.Ref == "" {
+		applyValidation(schema, field.Type, validationRules(field))
+	}
+	return schema
+}
+
+// structFields lists exported fields, flattening embedded structs the way
+// encoding/json does.
+func structFields(t reflect.Type) []reflect.StructField {
+	var fields []reflect.StructField
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				fields = append(fields, structFields(embedded)...)
+				continue
+			}
+		}
+		if field.IsExported() {
+			fields = append(fields, field)
+		}
+	}
+	return fields
+}
+
+func jsonName(field reflect.StructField) string {
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" {
+		return name
+	}
+	return field.Name
+}
+
+func validationRules(field reflect.StructField) []string {
+	return strings.Split(field.Tag.Get("validate"), ",")
+}
+
+// applyValidation maps validator rules onto JSON schema keywords. Rules after
+// dive describe the elements of a slice or map.
+func applyValidation(schema *jsonSchema, t reflect.Type, rules []string) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	for i, rule := range rules {
+		tag, param, _ := strings.Cut(rule, "=")
+		switch tag {
+		case "dive":
+			elem := schema.Items
+			if elem == nil {
+				elem = schema.AdditionalProperties
+			}
+			if elem != nil && elem.Ref == "" && (t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map) {
+				applyValidation(elem, t.Elem(), rules[i+1:])
+			}
+			return
+		case "email":
+			schema.Format = "email"
+		case "uuid", "uuid4":
+			schema.Format = "uuid"
+		case "url", "http_url", "uri":
+			schema.Format = "uri"
+		case "datetime":
+			schema.Format = "date-time"
+		case "ip":
+			schema.Format = "ip"
+		case "oneof":
+			for _, value := range strings.Fields(param) {
+				if n, err := strconv.ParseFloat(value, 64); err == nil && (schema.Type == "integer" || schema.Type == "number") {
+					schema.Enum = append(schema.Enum, n)
+				} else {
+					schema.Enum = append(schema.Enum, value)
+				}
+			}
+		case "min", "gte":
+			applyBound(schema, t, param, &schema.Minimum, &schema.MinLength, &schema.MinItems)
+		case "max", "lte":
+			applyBound(schema, t, param, &schema.Maximum, &schema.MaxLength, &schema.MaxItems)
+		case "len":
+			applyBound(schema, t, param, nil, &schema.MinLength, &schema.MinItems)
+			applyBound(schema, t, param, nil, &schema.MaxLength, &schema.MaxItems)
+		case "gt":
+			applyBound(schema, t, param, &schema.ExclusiveMinimum, nil, nil)
+		case "lt":
+			applyBound(schema, t, param, &schema.ExclusiveMaximum, nil, nil)
+		}
+	}
+}
+
+func applyBound(schema *jsonSchema, t reflect.Type, param string, number **float64, length, items **int) {
+	switch t.Kind() {
+	case reflect.String:
+		if n, err := strconv.Atoi(param); err == nil && length != nil {
+			*length = &n
+		}
+	case reflect.Slice, reflect.Array, reflect.Map:
+		if n, err := strconv.Atoi(param); err == nil && items != nil && schema.Type == "array" {
+			*items = &n
+		}
+	default:
+		if n, err := strconv.ParseFloat(param, 64); err == nil && number != nil && (schema.Type == "integer" || schema.Type == "number") {
+			*number = &n
+		}
+	}
+}
+
+type openAPI struct {
+	cfg     OpenAPIConfig
+	auth    *AuthConfig
+	exclude []string
+
+	once     sync.Once
+	document []byte
+	err      error
+}
+
+func newOpenAPI(cfg OpenAPIConfig, auth *AuthConfig, exclude ...string) *openAPI {
+	if cfg.Path == "" {
+		cfg.Path = "/openapi.json"
+	}
+	if cfg.UIPath == "" {
+		cfg.UIPath = "/docs"
+	}
+	if cfg.Title == "" {
+		cfg.Title = "API"
+	}
+	if cfg.Version == "" {
+		cfg.Version = "0.0.0"
+	}
+	exclude = append(exclude, cfg.Path)
+	if cfg.UI != nil {
+		exclude = append(exclude, cfg.UIPath)
+		for name := range swaggerUIAssets {
+			exclude = append(exclude, path.Join(cfg.UIPath, name))
+		}
+	}
+	return &openAPI{
+		cfg:     cfg,
+		auth:    auth,
+		exclude: exclude,
+	}
+}
+
+func (o *openAPI) register(app *fiber.App) error {
+	app.Get(o.cfg.Path, o.documentHandler)
+	if o.cfg.UI == nil || env.InProduction() {
+		return nil
+	}
+	app.Get(o.cfg.UIPath, o.uiHandler)
+	for name, contentType := range swaggerUIAssets {
+		asset, err := fs.ReadFile(o.cfg.UI, name)
+		if err != nil {
+			return fmt.Errorf("server: swagger ui: %w", err)
+		}
+		app.Get(path.Join(o.cfg.UIPath, name), func(c fiber.Ctx) error {
+			c.Set(fiber.HeaderContentType, contentType)
+			c.Set(fiber.HeaderCacheControl, "public, max-age=86400")
+			return c.Send(asset)
+		})
+	}
+	return nil
+}
+
+// documentHandler builds the document on first use, once the routes are registered.
+func (o *openAPI) documentHandler(c fiber.Ctx) error {
+	o.once.Do(func() {
+		o.document, o.err = json.Marshal(o.generate(c.App()))
+	})
+	if o.err != nil {
+		return o.err
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSONCharsetUTF8)
+	return c.Send(o.document)
+}
+
+// The page has no inline script so it also loads under a same-origin CSP.
+var swaggerUIPage = template.Must(template.New("swagger-ui").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.Title}}</title>
+<link rel="stylesheet" href="{{.UIPath}}/swagger-ui.css">
+</head>
+<body>
+<div id="swagger-ui" data-url="{{.Path}}"></div>
+<script src="{{.UIPath}}/swagger-ui-bundle.js"></script>
+<script src="{{.UIPath}}/init.js"></script>
+</body>
+</html>
+`))
+
+func (o *openAPI) uiHandler(c fiber.Ctx) error {
+	var page bytes.Buffer
+	if err := swaggerUIPage.Execute(&page, o.cfg); err != nil {
+		return err
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMETextHTMLCharsetUTF8)
+	return c.Send(page.Bytes())
+}
+
+func (o *openAPI) generate(app *fiber.App) *openAPIDocument {
+	doc := &openAPIDocument{
+		OpenAPI: "3.1.0",
+		Info: openAPIInfo{
+			Title:       o.cfg.Title,
+			Version:     o.cfg.Version,
+			Description: o.cfg.Description,
+		},
+		Paths: map[string]map[string]*openAPIOperation{},
+	}
+	for _, url := range o.cfg.Servers {
+		doc.Servers = append(doc.Servers, openAPIServer{URL: url})
+	}
+	if o.auth != nil {
+		doc.Components.SecuritySchemes = map[string]openAPISecurityScheme{
+			bearerSecurityScheme: {Type: "http", Scheme: "bearer", BearerFormat: "JWT"},
+		}
+		doc.Security = []map[string][]string{{bearerSecurityScheme: {}}}
+	}
+	schemas := newSchemaRegistry()
+	for _, route := range app.GetRoutes(true) {
+		if route.Method == fiber.MethodHead || route.Method == fiber.MethodOptions || slices.Contains(o.exclude, route.Path) {
+			continue
+		}
+		openAPIPath, params := openAPIPath(route.Path)
+		op := &openAPIOperation{
+			OperationID: operationID(route.Method, route.Path),
+			Responses:   map[string]*openAPIResponse{},
+		}
+		if route.Name != "" {
+			op.OperationID = route.Name
+		}
+		for _, name := range params {
+			op.Parameters = append(op.Parameters, &openAPIParameter{
+				Name:     name,
+				In:       "path",
+				Required: true,
+				Schema:   &jsonSchema{Type: "string"},
+			})
+		}
+		for _, handler := range route.Handlers {
+			for _, fn := range describeHandler(app, handler) {
+				fn(op, schemas)
+			}
+		}
+		if o.auth != nil && slices.Contains(o.auth.SkipPaths, route.Path) {
+			op.Security = []map[string][]string{}
+		}
+		op.Responses["default"] = problemResponse(schemas, "Error")
+		if doc.Paths[openAPIPath] == nil {
+			doc.Paths[openAPIPath] = map[string]*openAPIOperation{}
+		}
+		doc.Paths[openAPIPath][strings.ToLower(route.Method)] = op
+	}
+	doc.Components.Schemas = schemas.schemas
+	return doc
+}
+
+// openAPIPath rewrites fiber parameters such as :id<int>? and * into {id} and {wildcard}.
+func openAPIPath(route string) (string, []string) {
+	var b strings.Builder
+	var params []string
+	wildcards := 0
+	for i := 0; i < len(route); i++ {
+		switch ch := route[i]; {
+		case ch == '\\' && i+1 < len(route):
+			i++
+			b.WriteByte(route[i])
+		case ch == ':':
+			j := i + 1
+			for j < len(route) && (route[j] == '_' || isAlphanumeric(route[j])) {
+				j++
+			}
+			name := route[i+1 : j]
+			if j < len(route) && route[j] == '<' {
+				if end := strings.IndexByte(route[j:], '>'); end >= 0 {
+					j += end + 1
+				}
+			}
+			if j < len(route) && route[j] == '?' {
+				j++
+			}
+			params = append(params, name)
+			b.WriteString("{" + name + "}")
+			i = j - 1
+		case ch == '*' || ch == '+':
+			wildcards++
+			name := "wildcard"
+			if wildcards > 1 {
+				name += strconv.Itoa(wildcards)
+			}
+			params = append(params, name)
+			b.WriteString("{" + name + "}")
+		default:
+			b.WriteByte(ch)
+		}
+	}
+	return b.String(), params
+}
+
+// operationID derives camel-cased IDs such as getOrdersById from the route.
+func operationID(method, route string) string {
+	id := strings.ToLower(method)
+	for _, segment := range strings.Split(route, "/") {
+		if segment == "" {
+			continue
+		}
+		prefix := ""
+		if segment[0] == ':' {
+			prefix = "By"
+			segment, _, _ = strings.Cut(segment, "<")
+		}
+		for _, word := range strings.FieldsFunc(segment, func(r rune) bool { return r > 127 || !isAlphanumeric(byte(r)) }) {
+			id += prefix + strings.ToUpper(word[:1]) + word[1:]
+			prefix = ""
+		}
+	}
+	return id
+}
+
+func isAlphanumeric(ch byte) bool {
+	return ch >= 'a' && ch <= 'z' || ch >= 'A' && ch <= 'Z' || ch >= '0' && ch <= '9'
+}
@@ -0,0 +1,48 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/server/swaggerui"
+)
+
+func TestOpenAPIUI(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  OpenAPIConfig
+		want int
+	}{
+		{"not served without assets", OpenAPIConfig{}, fiber.StatusNotFound},
+		{"served with assets", OpenAPIConfig{UI: swaggerui.FS}, fiber.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := New(WithOpenAPI(tt.cfg))
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := s.Router()
+			for _, path := range []string{"/docs", "/docs/swagger-ui-bundle.js", "/docs/swagger-ui.css", "/docs/init.js"} {
+				res, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil))
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+				if res.StatusCode != tt.want {
+					t.Errorf("GET %s: status = %d, want %d", path, res.StatusCode, tt.want)
+				}
+			}
+			res, err := app.Test(httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+			if err != nil {
+				t.Fatal(err)
+			}
+			res.Body.Close()
+			if res.StatusCode != fiber.StatusOK {
+				t.Errorf("GET /openapi.json: status = %d, want %d", res.StatusCode, fiber.StatusOK)
+			}
+		})
+	}
+}
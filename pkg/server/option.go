@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"crypto/x509"
 	"errors"
@@ -11,8 +12,27 @@ type Option func(c *config)
 type config struct {
 	certificates []tls.Certificate
 	clientCAs    *x509.CertPool
+	tlsFiles     *TLSFiles
+	acme         *ACMEConfig
 
-	healthcheckPath string
+	healthcheckPath   string
+	readinessPath     string
+	health            *health
+	propagateDeadline bool
+	accessLog         *AccessLogConfig
+	cors              *CORSConfig
+	disableCORS       bool
+	metrics           *MetricsConfig
+	admin             *AdminConfig
+	auth              *AuthConfig
+	rateLimit         *RateLimitConfig
+	concurrencyLimit  *ConcurrencyLimitConfig
+	idempotency       *IdempotencyConfig
+	openAPI           *OpenAPIConfig
+	timeout           *TimeoutConfig
+	proxy             *ProxyConfig
+	shutdown          ShutdownConfig
+	shutdownHooks     []shutdownHook
 	error
 }
 
@@ -39,8 +59,163 @@ func WithClientAuth(ca []byte) Option {
 	}
 }
 
+// WithTLSFiles serves TLS from files reloaded on change, instead of
+// WithCertificate and WithClientAuth.
+func WithTLSFiles(files TLSFiles) Option {
+	return func(c *config) {
+		c.tlsFiles = &files
+	}
+}
+
+// WithACME obtains and renews certificates from an ACME CA such as Let's Encrypt.
+func WithACME(cfg ACMEConfig) Option {
+	return func(c *config) {
+		c.acme = &cfg
+	}
+}
+
 func WithCustomHealthcheckPath(path string) Option {
 	return func(c *config) {
 		c.healthcheckPath = path
 	}
 }
+
+func WithCustomReadinessPath(path string) Option {
+	return func(c *config) {
+		c.readinessPath = path
+	}
+}
+
+func WithHealthCheck(name string, checker HealthChecker, cfg HealthCheckConfig) Option {
+	return func(c *config) {
+		c.health.add(name, checker, cfg)
+	}
+}
+
+// WithDeadlinePropagation bounds each request by the caller's TimeoutHeader,
+// capped at five minutes.
+func WithDeadlinePropagation() Option {
+	return func(c *config) {
+		c.propagateDeadline = true
+	}
+}
+
+func WithAccessLog(cfg AccessLogConfig) Option {
+	return func(c *config) {
+		c.accessLog = &cfg
+	}
+}
+
+func WithCORS(cfg CORSConfig) Option {
+	return func(c *config) {
+		c.cors = &cfg
+	}
+}
+
+func WithoutCORS() Option {
+	return func(c *config) {
+		c.disableCORS = true
+	}
+}
+
+func WithMetrics(cfg MetricsConfig) Option {
+	return func(c *config) {
+		c.metrics = &cfg
+	}
+}
+
+func WithAdminServer(cfg AdminConfig) Option {
+	return func(c *config) {
+		if cfg.Port == "" {
+			c.error = errors.New("server: admin port is required")
+			return
+		}
+		c.admin = &cfg
+	}
+}
+
+// WithAuth requires a valid bearer JWT on every route registered on the router,
+// except the configured skip paths and the health and metrics endpoints.
+func WithAuth(cfg AuthConfig) Option {
+	return func(c *config) {
+		c.auth = &cfg
+	}
+}
+
+// WithGracefulShutdown overrides the drain timings; zero fields keep their defaults.
+func WithGracefulShutdown(cfg ShutdownConfig) Option {
+	return func(c *config) {
+		c.shutdown.PreStopDelay = cfg.PreStopDelay
+		if cfg.DrainTimeout > 0 {
+			c.shutdown.DrainTimeout = cfg.DrainTimeout
+		}
+		if cfg.HookTimeout > 0 {
+			c.shutdown.HookTimeout = cfg.HookTimeout
+		}
+	}
+}
+
+// WithShutdownHook runs fn after the server has drained, e.g. to close a
+// database pool. Hooks run in registration order, before telemetry is flushed.
+func WithShutdownHook(name string, fn func(ctx context.Context) error) Option {
+	return func(c *config) {
+		c.shutdownHooks = append(c.shutdownHooks, shutdownHook{name, fn})
+	}
+}
+
+// WithRateLimit limits every route registered on the router; use RateLimit
+// for limits on individual routes.
+func WithRateLimit(cfg RateLimitConfig) Option {
+	return func(c *config) {
+		if cfg.PerRoute {
+			c.error = errors.New("server: per-route limits need RateLimit on the routes")
+			return
+		}
+		c.rateLimit = &cfg
+	}
+}
+
+// WithConcurrencyLimit sheds load above an adaptive concurrency limit. Its
+// metrics are exported when WithMetrics is also set.
+func WithConcurrencyLimit(cfg ConcurrencyLimitConfig) Option {
+	return func(c *config) {
+		c.concurrencyLimit = &cfg
+	}
+}
+
+// WithIdempotency replays stored responses for repeated Idempotency-Key
+// requests on every route; use Idempotency for individual routes.
+func WithIdempotency(cfg IdempotencyConfig) Option {
+	return func(c *config) {
+		c.idempotency = &cfg
+	}
+}
+
+// WithTimeout bounds every request with a context deadline; use Timeout to
+// give individual routes a different budget. Combine it with
+// WithDeadlinePropagation to also honour the caller's TimeoutHeader.
+func WithTimeout(cfg TimeoutConfig) Option {
+	return func(c *config) {
+		if cfg.Default <= 0 {
+			c.error = errors.New("server: timeout needs a positive default")
+			return
+		}
+		c.timeout = &cfg
+	}
+}
+
+// WithOpenAPI serves an OpenAPI 3.1 document describing the routes on the
+// router. Set OpenAPIConfig.UI to swaggerui.FS to also serve Swagger UI.
+func WithOpenAPI(cfg OpenAPIConfig) Option {
+	return func(c *config) {
+		c.openAPI = &cfg
+	}
+}
+
+// WithTrustedProxies reads the client address from a header set by trusted
+// load balancers.
+func WithTrustedProxies(cfg ProxyConfig) Option {
+	return func(c *config) {
+		c.proxy = &cfg
+	}
+}
@@ -0,0 +1,118 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/env"
+	"github.com/nphiro/mesh/pkg/xerrors"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const problemContentType = "application/problem+json"
+
+// Problem is an RFC 9457 problem detail. Handlers may return it directly as an error.
+type Problem struct {
+	Type     string `json:"type"`
+	Title    string `json:"title"`
+	Status   int    `json:"status"`
+	Detail   string `json:"detail,omitempty"`
+	Instance string `json:"instance,omitempty"`
+	TraceID  string `json:"trace_id,omitempty"`
+
+	Errors []FieldError `json:"errors,omitempty"`
+}
+
+type FieldError struct {
+	Field   string `json:"field"`
+	In      string `json:"in"`
+	Message string `json:"message"`
+}
+
+func NewProblem(status int, detail string) *Problem {
+	return &Problem{
+		Type:   "about:blank",
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+	}
+}
+
+func (p *Problem) Error() string {
+	if p.Detail == "" {
+		return p.Title
+	}
+	return p.Title + ": " + p.Detail
+}
+
+var kindStatus = map[xerrors.Kind]int{
+	xerrors.KindInvalidArgument:  fiber.StatusBadRequest,
+	xerrors.KindUnauthenticated:  fiber.StatusUnauthorized,
+	xerrors.KindPermissionDenied: fiber.StatusForbidden,
+	xerrors.KindNotFound:         fiber.StatusNotFound,
+	xerrors.KindConflict:         fiber.StatusConflict,
+	xerrors.KindPrecondition:     fiber.StatusPreconditionFailed,
+	xerrors.KindRateLimited:      fiber.StatusTooManyRequests,
+	xerrors.KindUnavailable:      fiber.StatusServiceUnavailable,
+	xerrors.KindTimeout:          fiber.StatusGatewayTimeout,
+	xerrors.KindInternal:         fiber.StatusInternalServerError,
+}
+
+func problemFromError(err error) (*Problem, bool) {
+	var problem *Problem
+	if errors.As(err, &problem) {
+		p := *problem
+		return &p, false
+	}
+	var fiberErr *fiber.Error
+	if errors.As(err, &fiberErr) {
+		problem = NewProblem(fiberErr.Code, fiberErr.Message)
+		if problem.Detail == problem.Title {
+			problem.Detail = ""
+		}
+		return problem, false
+	}
+	// An expired deadline is an expected outcome of timeouts, not a server fault.
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewProblem(fiber.StatusGatewayTimeout, "deadline exceeded"), false
+	}
+	status, ok := kindStatus[xerrors.KindOf(err)]
+	if !ok {
+		status = fiber.StatusInternalServerError
+	}
+	return NewProblem(status, err.Error()), true
+}
+
+// errorHandler renders every error returned from a handler as problem+json.
+// Server errors hide their message in production and are logged with stack frames;
+// fiber errors and problems are treated as deliberate responses and not logged.
+func errorHandler(c fiber.Ctx, err error) error {
+	problem, unexpected := problemFromError(err)
+	if problem.Instance == "" {
+		problem.Instance = strings.Clone(c.Path())
+	}
+	if spanContext := trace.SpanContextFromContext(c.Context()); spanContext.HasTraceID() {
+		problem.TraceID = spanContext.TraceID().String()
+	}
+	if problem.Status >= fiber.StatusInternalServerError {
+		if unexpected {
+			var framed interface{ StackFrames() []uintptr }
+			if !errors.As(err, &framed) {
+				err = xerrors.Wrap(err)
+			}
+			slog.ErrorContext(c.Context(), "Request failed",
+				slog.Any("error", err),
+				slog.String("request_method", strings.Clone(c.Method())),
+				slog.String("request_path", problem.Instance),
+			)
+		}
+		if env.InProduction() {
+			problem.Detail = ""
+		}
+	}
+	return c.Status(problem.Status).JSON(problem, problemContentType)
+}
@@ -0,0 +1,121 @@
+package server
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+// ProxyConfig names the load balancers whose forwarded client address is
+// believed. Without it ClientIP, used by RateLimitByIP and the access log, is
+// the address of the last hop, so clients behind one balancer share it.
+//
+// Each proxy appends the address it received the request from, so only the
+// right end of the header can be trusted: anything further left may have been
+// sent by the client. The client address is the rightmost entry that is not a
+// trusted proxy. List every hop, e.g. a CDN in front of the load balancer,
+// or the CDN's address is taken for the client.
+type ProxyConfig struct {
+	// Header carries the client address. Defaults to X-Forwarded-For.
+	Header string
+	// Proxies are trusted proxy addresses or CIDR ranges.
+	Proxies []string
+	// Private trusts every private network range, e.g. inside a cluster.
+	Private bool
+}
+
+func (p ProxyConfig) apply(cfg *fiber.Config) {
+	// c.IP() stays the peer address, since Fiber believes the leftmost
+	// forwarded entry. Trusting the proxy still lets c.Scheme() and
+	// c.Hostname() read X-Forwarded-Proto and X-Forwarded-Host.
+	cfg.TrustProxy = true
+	cfg.TrustProxyConfig = fiber.TrustProxyConfig{
+		Proxies: p.Proxies,
+		Private: p.Private,
+	}
+}
+
+var clientIPKey localsKey = "client_ip"
+
+type proxyResolver struct {
+	header   string
+	prefixes []netip.Prefix
+	private  bool
+}
+
+func newProxyResolver(p ProxyConfig) (*proxyResolver, error) {
+	r := &proxyResolver{header: p.Header, private: p.Private}
+	if r.header == "" {
+		r.header = fiber.HeaderXForwardedFor
+	}
+	for _, proxy := range p.Proxies {
+		if strings.Contains(proxy, "/") {
+			prefix, err := netip.ParsePrefix(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("server: trusted proxy %q: %w", proxy, err)
+			}
+			r.prefixes = append(r.prefixes, prefix.Masked())
+			continue
+		}
+		addr, err := netip.ParseAddr(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("server: trusted proxy %q: %w", proxy, err)
+		}
+		r.prefixes = append(r.prefixes, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+	}
+	return r, nil
+}
+
+func (r *proxyResolver) trusted(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	if r.private && addr.IsPrivate() {
+		return true
+	}
+	for _, prefix := range r.prefixes {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// clientIP walks the forwarded entries from the right, past trusted proxies.
+// An entry that is not an address ends the walk at the last hop before it.
+func (r *proxyResolver) clientIP(c fiber.Ctx) string {
+	client := c.IP()
+	addr, err := netip.ParseAddr(client)
+	if err != nil || !r.trusted(addr) {
+		return client
+	}
+	values := c.Request().Header.PeekAll(r.header)
+	for i := len(values) - 1; i >= 0; i-- {
+		entries := strings.Split(string(values[i]), ",")
+		for j := len(entries) - 1; j >= 0; j-- {
+			addr, err := netip.ParseAddr(strings.TrimSpace(entries[j]))
+			if err != nil {
+				return client
+			}
+			client = addr.Unmap().String()
+			if !r.trusted(addr) {
+				return client
+			}
+		}
+	}
+	return client
+}
+
+func (r *proxyResolver) middleware(c fiber.Ctx) error {
+	c.Locals(clientIPKey, r.clientIP(c))
+	return c.Next()
+}
+
+// ClientIP is the address of the client behind the proxies trusted by
+// WithTrustedProxies, or the peer address without it.
+func ClientIP(c fiber.Ctx) string {
+	if ip, ok := c.Locals(clientIPKey).(string); ok {
+		return ip
+	}
+	return c.IP()
+}
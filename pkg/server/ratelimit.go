@@ -0,0 +1,277 @@
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type RateLimitAlgorithm int
+
+const (
+	// TokenBucket allows bursts of up to Limit requests, refilled evenly over Window.
+	TokenBucket RateLimitAlgorithm = iota
+	// SlidingWindow allows Limit requests in any Window, weighting the previous
+	// fixed window by how much of it still overlaps.
+	SlidingWindow
+)
+
+// RateLimitKeyFunc identifies the client a request is counted against.
+// An empty key leaves the request unlimited.
+type RateLimitKeyFunc func(c fiber.Ctx) string
+
+type RateLimitConfig struct {
+	Algorithm RateLimitAlgorithm
+	Limit     int
+	Window    time.Duration
+	// Key defaults to RateLimitByIP.
+	Key RateLimitKeyFunc
+	// PerRoute counts each route separately when one RateLimit handler guards
+	// several routes. The route is unknown before routing, so WithRateLimit
+	// rejects it.
+	PerRoute bool
+	// Store defaults to a store in process memory. Limiters sharing a
+	// distributed store need distinct Prefixes.
+	Store  RateLimitStore
+	Prefix string
+	// SkipPaths are never limited.
+	SkipPaths []string
+}
+
+// RateLimitStore holds limiter state, e.g. in Redis to share limits across
+// instances. Update must apply fn atomically for the key and keep the result
+// for at least ttl.
+type RateLimitStore interface {
+	Update(ctx context.Context, key string, ttl time.Duration, fn func(state []byte) []byte) error
+}
+
+// storeFailureLogInterval spaces out logs of a failing store, which would
+// otherwise fire on every request during an outage.
+const storeFailureLogInterval = time.Minute
+
+// RateLimitByIP keys by ClientIP, which is the load balancer's address unless
+// WithTrustedProxies names it.
+func RateLimitByIP(c fiber.Ctx) string {
+	return "ip:" + ClientIP(c)
+}
+
+// RateLimitByHeader keys by a header such as X-Api-Key. Values are hashed so
+// secrets never reach the store.
+func RateLimitByHeader(name string) RateLimitKeyFunc {
+	return func(c fiber.Ctx) string {
+		value := c.Get(name)
+		if value == "" {
+			return ""
+		}
+		sum := sha256.Sum256([]byte(value))
+		return "header:" + hex.EncodeToString(sum[:16])
+	}
+}
+
+// RateLimitBySubject keys by the JWT subject, falling back to the client IP
+// for unauthenticated requests.
+func RateLimitBySubject(c fiber.Ctx) string {
+	if claims, ok := ClaimsFromContext(c.Context()); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	return RateLimitByIP(c)
+}
+
+// RateLimit is route middleware limiting requests per client. It panics on an
+// invalid config; see WithRateLimit to guard every route.
+func RateLimit(cfg RateLimitConfig) fiber.Handler {
+	handler, err := rateLimitMiddleware(cfg)
+	if err != nil {
+		panic(err.Error())
+	}
+	return handler
+}
+
+type rateLimitDecision struct {
+	allowed   bool
+	remaining int
+	reset     time.Duration
+}
+
+func rateLimitMiddleware(cfg RateLimitConfig) (fiber.Handler, error) {
+	if cfg.Limit <= 0 || cfg.Window <= 0 {
+		return nil, errors.New("server: rate limit needs a positive limit and window")
+	}
+	if cfg.Algorithm != TokenBucket && cfg.Algorithm != SlidingWindow {
+		return nil, fmt.Errorf("server: unknown rate limit algorithm %d", cfg.Algorithm)
+	}
+	if cfg.Key == nil {
+		cfg.Key = RateLimitByIP
+	}
+	if cfg.Store == nil {
+		cfg.Store = NewMemoryRateLimitStore()
+	}
+	policy := fmt.Sprintf("%d;w=%d", cfg.Limit, int(math.Ceil(cfg.Window.Seconds())))
+	var lastFailureLog atomic.Int64
+	var failures atomic.Int64
+
+	return func(c fiber.Ctx) error {
+		if slices.Contains(cfg.SkipPaths, c.Path()) {
+			return c.Next()
+		}
+		key := cfg.Key(c)
+		if key == "" {
+			return c.Next()
+		}
+		if cfg.PerRoute {
+			key = c.Method() + " " + routeLabel(c) + "|" + key
+		}
+		// Keys built from request values would otherwise alias fiber's reused buffers.
+		key = strings.Clone(cfg.Prefix + key)
+
+		var decision rateLimitDecision
+		now := time.Now()
+		err := cfg.Store.Update(c.Context(), key, 2*cfg.Window, func(state []byte) []byte {
+			if cfg.Algorithm == SlidingWindow {
+				state, decision = slidingWindow(state, now, cfg.Limit, cfg.Window)
+			} else {
+				state, decision = tokenBucket(state, now, cfg.Limit, cfg.Window)
+			}
+			return state
+		})
+		if err != nil {
+			// Fail open: an unavailable store should not take the service down.
+			failures.Add(1)
+			last := lastFailureLog.Load()
+			if now.UnixNano()-last >= int64(storeFailureLogInterval) && lastFailureLog.CompareAndSwap(last, now.UnixNano()) {
+				slog.WarnContext(c.Context(), "Rate limit store failed", slog.Any("error", err), slog.Int64("failures", failures.Swap(0)))
+			}
+			return c.Next()
+		}
+
+		reset := strconv.Itoa(int(math.Ceil(decision.reset.Seconds())))
+		c.Set("RateLimit-Policy", policy)
+		c.Set("RateLimit-Limit", strconv.Itoa(cfg.Limit))
+		c.Set("RateLimit-Remaining", strconv.Itoa(decision.remaining))
+		c.Set("RateLimit-Reset", reset)
+		if !decision.allowed {
+			c.Set(fiber.HeaderRetryAfter, reset)
+			return NewProblem(fiber.StatusTooManyRequests, "rate limit exceeded")
+		}
+		return c.Next()
+	}, nil
+}
+
+// tokenBucket state is the token count and the time it was last refilled.
+func tokenBucket(state []byte, now time.Time, limit int, window time.Duration) ([]byte, rateLimitDecision) {
+	capacity := float64(limit)
+	perToken := window / time.Duration(limit)
+	tokens, last := capacity, now
+	if len(state) == 16 {
+		tokens = math.Float64frombits(binary.BigEndian.Uint64(state))
+		last = time.Unix(0, int64(binary.BigEndian.Uint64(state[8:])))
+		tokens = math.Min(capacity, tokens+float64(now.Sub(last))/float64(perToken))
+	}
+	decision := rateLimitDecision{allowed: tokens >= 1}
+	if decision.allowed {
+		tokens--
+		decision.reset = time.Duration((capacity - tokens) * float64(perToken))
+	} else {
+		decision.reset = time.Duration((1 - tokens) * float64(perToken))
+	}
+	decision.remaining = int(tokens)
+
+	state = make([]byte, 16)
+	binary.BigEndian.PutUint64(state, math.Float64bits(tokens))
+	binary.BigEndian.PutUint64(state[8:], uint64(now.UnixNano()))
+	return state, decision
+}
+
+// slidingWindow state is the current fixed window's index and count, and the
+// previous window's count.
+func slidingWindow(state []byte, now time.Time, limit int, window time.Duration) ([]byte, rateLimitDecision) {
+	index := now.UnixNano() / int64(window)
+	var count, previous int64
+	if len(state) == 24 {
+		switch storedIndex := int64(binary.BigEndian.Uint64(state)); storedIndex {
+		case index:
+			count = int64(binary.BigEndian.Uint64(state[8:]))
+			previous = int64(binary.BigEndian.Uint64(state[16:]))
+		case index - 1:
+			previous = int64(binary.BigEndian.Uint64(state[8:]))
+		}
+	}
+	elapsed := time.Duration(now.UnixNano() - index*int64(window))
+	weight := 1 - float64(elapsed)/float64(window)
+	used := float64(previous)*weight + float64(count)
+
+	decision := rateLimitDecision{allowed: used+1 <= float64(limit)}
+	if decision.allowed {
+		count++
+		used++
+	}
+	decision.remaining = max(0, limit-int(math.Ceil(used)))
+	switch {
+	case decision.allowed || count >= int64(limit):
+		decision.reset = window - elapsed
+	default:
+		// Wait until enough of the previous window has slid out for one more request.
+		needed := 1 - (float64(limit) - used)
+		decision.reset = time.Duration(needed / float64(previous) * float64(window))
+	}
+
+	state = make([]byte, 24)
+	binary.BigEndian.PutUint64(state, uint64(index))
+	binary.BigEndian.PutUint64(state[8:], uint64(count))
+	binary.BigEndian.PutUint64(state[16:], uint64(previous))
+	return state, decision
+}
+
+type memoryRateLimitEntry struct {
+	state   []byte
+	expires time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu        sync.Mutex
+	entries   map[string]memoryRateLimitEntry
+	lastSweep time.Time
+}
+
+func NewMemoryRateLimitStore() RateLimitStore {
+	return &memoryRateLimitStore{
+		entries:   map[string]memoryRateLimitEntry{},
+		lastSweep: time.Now(),
+	}
+}
+
+func (s *memoryRateLimitStore) Update(_ context.Context, key string, ttl time.Duration, fn func(state []byte) []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, entry := range s.entries {
+			if now.After(entry.expires) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	entry := s.entries[key]
+	if now.After(entry.expires) {
+		entry.state = nil
+	}
+	s.entries[key] = memoryRateLimitEntry{
+		state:   fn(entry.state),
+		expires: now.Add(ttl),
+	}
+	return nil
+}
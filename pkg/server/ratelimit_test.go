@@ -0,0 +1,266 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+)
+
+type rateLimitStep struct {
+	at            time.Duration
+	times         int
+	wantAllowed   bool
+	wantRemaining int
+	wantReset     time.Duration
+}
+
+func runRateLimitSteps(t *testing.T, fn func(state []byte, now time.Time) ([]byte, rateLimitDecision), steps []rateLimitStep) {
+	t.Helper()
+	// Aligned to the window so sliding window indexes are predictable.
+	base := time.Unix(1_000_000, 0)
+	var state []byte
+	for i, step := range steps {
+		var decision rateLimitDecision
+		for range max(1, step.times) {
+			state, decision = fn(state, base.Add(step.at))
+		}
+		if decision.allowed != step.wantAllowed || decision.remaining != step.wantRemaining || decision.reset != step.wantReset {
+			t.Errorf("step %d at %s: got allowed=%t remaining=%d reset=%s, want allowed=%t remaining=%d reset=%s",
+				i, step.at, decision.allowed, decision.remaining, decision.reset,
+				step.wantAllowed, step.wantRemaining, step.wantReset)
+		}
+	}
+}
+
+func TestTokenBucket(t *testing.T) {
+	const limit, window = 10, 10 * time.Second
+	tests := []struct {
+		name  string
+		steps []rateLimitStep
+	}{
+		{
+			name: "first request",
+			steps: []rateLimitStep{
+				{at: 0, wantAllowed: true, wantRemaining: 9, wantReset: time.Second},
+			},
+		},
+		{
+			name: "burst up to the limit",
+			steps: []rateLimitStep{
+				{at: 0, times: 10, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				{at: 0, wantAllowed: false, wantRemaining: 0, wantReset: time.Second},
+			},
+		},
+		{
+			name: "refills a token every window divided by limit",
+			steps: []rateLimitStep{
+				{at: 0, times: 10, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				{at: 500 * time.Millisecond, wantAllowed: false, wantRemaining: 0, wantReset: 500 * time.Millisecond},
+				{at: time.Second, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				{at: 3 * time.Second, wantAllowed: true, wantRemaining: 1, wantReset: 9 * time.Second},
+			},
+		},
+		{
+			name: "caps refill at the limit",
+			steps: []rateLimitStep{
+				{at: 0, times: 10, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				{at: time.Hour, wantAllowed: true, wantRemaining: 9, wantReset: time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runRateLimitSteps(t, func(state []byte, now time.Time) ([]byte, rateLimitDecision) {
+				return tokenBucket(state, now, limit, window)
+			}, tt.steps)
+		})
+	}
+}
+
+func TestSlidingWindow(t *testing.T) {
+	const limit, window = 10, 10 * time.Second
+	tests := []struct {
+		name  string
+		steps []rateLimitStep
+	}{
+		{
+			name: "fills the window",
+			steps: []rateLimitStep{
+				{at: 0, times: 10, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				{at: 4 * time.Second, wantAllowed: false, wantRemaining: 0, wantReset: 6 * time.Second},
+			},
+		},
+		{
+			name: "weights the previous window by its overlap",
+			steps: []rateLimitStep{
+				{at: 0, times: 10, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				// Half of the previous window still counts: 5 + 1.
+				{at: 15 * time.Second, wantAllowed: true, wantRemaining: 4, wantReset: 5 * time.Second},
+				{at: 15 * time.Second, times: 4, wantAllowed: true, wantRemaining: 0, wantReset: 5 * time.Second},
+				// One more fits once a tenth of the previous window has slid out.
+				{at: 15 * time.Second, wantAllowed: false, wantRemaining: 0, wantReset: time.Second},
+			},
+		},
+		{
+			name: "rolls the current window into the previous one",
+			steps: []rateLimitStep{
+				{at: 12 * time.Second, times: 5, wantAllowed: true, wantRemaining: 5, wantReset: 8 * time.Second},
+				{at: 25 * time.Second, wantAllowed: true, wantRemaining: 6, wantReset: 5 * time.Second},
+			},
+		},
+		{
+			name: "forgets windows older than the previous one",
+			steps: []rateLimitStep{
+				{at: 0, times: 10, wantAllowed: true, wantRemaining: 0, wantReset: 10 * time.Second},
+				{at: 25 * time.Second, wantAllowed: true, wantRemaining: 9, wantReset: 5 * time.Second},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			runRateLimitSteps(t, func(state []byte, now time.Time) ([]byte, rateLimitDecision) {
+				return slidingWindow(state, now, limit, window)
+			}, tt.steps)
+		})
+	}
+}
+
+type failingRateLimitStore struct{}
+
+func (failingRateLimitStore) Update(context.Context, string, time.Duration, func([]byte) []byte) error {
+	return errors.New("store unavailable")
+}
+
+func TestRateLimitMiddleware(t *testing.T) {
+	type request struct {
+		forwardedFor  string
+		wantStatus    int
+		wantRemaining string
+	}
+	tests := []struct {
+		name     string
+		opts     []Option
+		requests []request
+	}{
+		{
+			name: "limits per client",
+			requests: []request{
+				{"", fiber.StatusOK, "1"},
+				{"", fiber.StatusOK, "0"},
+				{"", fiber.StatusTooManyRequests, "0"},
+			},
+		},
+		{
+			name: "ignores forwarded addresses from untrusted peers",
+			requests: []request{
+				{"203.0.113.1", fiber.StatusOK, "1"},
+				{"203.0.113.2", fiber.StatusOK, "0"},
+				{"203.0.113.3", fiber.StatusTooManyRequests, "0"},
+			},
+		},
+		{
+			name: "keys by forwarded address from trusted proxies",
+			opts: []Option{WithTrustedProxies(ProxyConfig{Proxies: []string{"0.0.0.0"}})},
+			requests: []request{
+				{"203.0.113.1", fiber.StatusOK, "1"},
+				{"203.0.113.1", fiber.StatusOK, "0"},
+				{"203.0.113.2", fiber.StatusOK, "1"},
+				{"203.0.113.1", fiber.StatusTooManyRequests, "0"},
+			},
+		},
+		{
+			name: "ignores spoofed entries left of the client",
+			opts: []Option{WithTrustedProxies(ProxyConfig{Proxies: []string{"0.0.0.0"}})},
+			requests: []request{
+				{"1.1.1.1, 203.0.113.1", fiber.StatusOK, "1"},
+				{"1.1.1.2, 203.0.113.1", fiber.StatusOK, "0"},
+				{"1.1.1.3, 203.0.113.1", fiber.StatusTooManyRequests, "0"},
+			},
+		},
+		{
+			name: "skips every trusted hop",
+			opts: []Option{WithTrustedProxies(ProxyConfig{Proxies: []string{"0.0.0.0", "10.0.0.0/8"}})},
+			requests: []request{
+				{"1.1.1.1, 203.0.113.1, 10.0.0.7", fiber.StatusOK, "1"},
+				{"203.0.113.1, 10.0.0.8", fiber.StatusOK, "0"},
+				{"203.0.113.2, 10.0.0.7", fiber.StatusOK, "1"},
+			},
+		},
+		{
+			name: "stops at entries that are not addresses",
+			opts: []Option{WithTrustedProxies(ProxyConfig{Proxies: []string{"0.0.0.0"}})},
+			requests: []request{
+				{"203.0.113.1, unknown", fiber.StatusOK, "1"},
+				{"203.0.113.2, unknown", fiber.StatusOK, "0"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithRateLimit(RateLimitConfig{Limit: 2, Window: time.Minute})}, tt.opts...)
+			s, err := New(opts...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			app := s.Router()
+			app.Get("/orders", func(c fiber.Ctx) error {
+				return c.SendStatus(fiber.StatusOK)
+			})
+			for i, r := range tt.requests {
+				req := httptest.NewRequest(http.MethodGet, "/orders", nil)
+				if r.forwardedFor != "" {
+					req.Header.Set(fiber.HeaderXForwardedFor, r.forwardedFor)
+				}
+				res, err := app.Test(req)
+				if err != nil {
+					t.Fatal(err)
+				}
+				res.Body.Close()
+				if res.StatusCode != r.wantStatus {
+					t.Errorf("request %d: status = %d, want %d", i, res.StatusCode, r.wantStatus)
+				}
+				if got := res.Header.Get("RateLimit-Remaining"); got != r.wantRemaining {
+					t.Errorf("request %d: RateLimit-Remaining = %q, want %q", i, got, r.wantRemaining)
+				}
+				if got := res.Header.Get("RateLimit-Policy"); got != "2;w=60" {
+					t.Errorf("request %d: RateLimit-Policy = %q", i, got)
+				}
+				if r.wantStatus == fiber.StatusTooManyRequests && res.Header.Get(fiber.HeaderRetryAfter) == "" {
+					t.Errorf("request %d: missing Retry-After", i)
+				}
+			}
+		})
+	}
+}
+
+func TestWithTrustedProxiesRejectsInvalidAddresses(t *testing.T) {
+	if _, err := New(WithTrustedProxies(ProxyConfig{Proxies: []string{"10.0.0.0/33"}})); err == nil {
+		t.Fatal("New accepted an invalid proxy range")
+	}
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	s, err := New(WithRateLimit(RateLimitConfig{Limit: 1, Window: time.Minute, Store: failingRateLimitStore{}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	app := s.Router()
+	app.Get("/orders", func(c fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	for i := range 3 {
+		res, err := app.Test(httptest.NewRequest(http.MethodGet, "/orders", nil))
+		if err != nil {
+			t.Fatal(err)
+		}
+		res.Body.Close()
+		if res.StatusCode != fiber.StatusOK {
+			t.Errorf("request %d: status = %d, want %d", i, res.StatusCode, fiber.StatusOK)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package server
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/xerrors"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// recoverMiddleware turns a handler panic into a 500 response. The panic is wrapped
+// by xerrors where it is recovered, so the stack frames still include the handler,
+// and logged at Error so cmlt forwards it to Sentry linked to the request trace.
+func recoverMiddleware(c fiber.Ctx) (err error) {
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		var panicErr error
+		if e, ok := r.(error); ok {
+			panicErr = xerrors.Wrap(fmt.Errorf("panic: %w", e))
+		} else {
+			panicErr = xerrors.Wrap(fmt.Errorf("panic: %v", r))
+		}
+
+		ctx := c.Context()
+		span := trace.SpanFromContext(ctx)
+		span.RecordError(panicErr, trace.WithStackTrace(true))
+		span.SetStatus(codes.Error, "panic")
+		slog.ErrorContext(ctx, "Recovered from panic",
+			slog.Any("error", panicErr),
+			slog.String("request_method", strings.Clone(c.Method())),
+			slog.String("request_path", strings.Clone(c.Path())),
+		)
+		err = fiber.ErrInternalServerError
+	}()
+	return c.Next()
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/google/uuid"
+	"github.com/nphiro/mesh/pkg/cmlt"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const maxRequestIDLength = 128
+
+// requestIDMiddleware keeps a valid caller-supplied X-Request-ID, or generates one,
+// echoes it on the response and tags the request context for logs and outgoing calls.
+func requestIDMiddleware(c fiber.Ctx) error {
+	id := c.Get(fiber.HeaderXRequestID)
+	if validRequestID(id) {
+		id = strings.Clone(id)
+	} else {
+		id = uuid.NewString()
+	}
+	c.Set(fiber.HeaderXRequestID, id)
+	ctx := cmlt.ContextWithRequestID(c.Context(), id)
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("request_id", id))
+	c.SetContext(ctx)
+	return c.Next()
+}
+
+// validRequestID accepts printable ASCII only, so a forged header cannot
+// inject control characters or unbounded data into logs.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] < '!' || id[i] > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -3,17 +3,23 @@ package server
 import (
 	"context"
 	"crypto/tls"
+	"errors"
 	"fmt"
 	"log/slog"
+	"net"
 	"os"
 	"os/signal"
 	"strings"
+	"syscall"
+	"time"
 
 	"github.com/gofiber/fiber/v3"
-	"github.com/gofiber/fiber/v3/middleware/cors"
-	"golang.org/x/crypto/acme/autocert"
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/crypto/acme"
 )
 
+const adminShutdownTimeout = 5 * time.Second
+
 type Server interface {
 	Router() *fiber.App
 	Run(ctx context.Context, port string) error
@@ -22,11 +28,28 @@ type Server interface {
 type server struct {
 	app          *fiber.App
 	listenConfig fiber.ListenConfig
+	health       *health
+
+	admin         *fiber.App
+	adminConfig   *AdminConfig
+	tlsConfig     *tls.Config
+	certReloader  *certReloader
+	jwks          *jwks
+	acmeChallenge *acmeChallengeServer
+	shutdown      ShutdownConfig
+	shutdownHooks []shutdownHook
+	inFlight      *inFlight
 }
 
 func New(opts ...Option) (Server, error) {
 	c := &config{
 		healthcheckPath: "/healthz",
+		readinessPath:   "/readyz",
+		health:          &health{},
+		shutdown: ShutdownConfig{
+			DrainTimeout: 10 * time.Second,
+			HookTimeout:  10 * time.Second,
+		},
 	}
 	for _, opt := range opts {
 		opt(c)
@@ -34,31 +57,177 @@ func New(opts ...Option) (Server, error) {
 			return nil, c.error
 		}
 	}
-	app := fiber.New()
-	app.Get(c.healthcheckPath, func(c fiber.Ctx) error {
-		return c.SendString("OK")
-	})
-	app.Use(cors.New()) // TODO: Add cors config
+	appConfig := fiber.Config{
+		ErrorHandler: errorHandler,
+	}
+	var proxy *proxyResolver
+	if c.proxy != nil {
+		var err error
+		if proxy, err = newProxyResolver(*c.proxy); err != nil {
+			return nil, err
+		}
+		c.proxy.apply(&appConfig)
+	}
+	app := fiber.New(appConfig)
+	// Operational endpoints move off the public port when an admin listener is configured.
+	ops := app
+	var admin *fiber.App
+	if c.admin != nil {
+		admin = newAdminApp()
+		ops = admin
+	}
+	ops.Get(c.healthcheckPath, c.health.livenessHandler)
+	ops.Get(c.readinessPath, c.health.readinessHandler)
+	var metrics *metrics
+	if c.metrics != nil {
+		var err error
+		if metrics, err = newMetrics(*c.metrics); err != nil {
+			return nil, err
+		}
+		ops.Get(metrics.path, metrics.handler())
+	}
+	if c.openAPI != nil {
+		exclude := []string{c.healthcheckPath, c.readinessPath}
+		if metrics != nil {
+			exclude = append(exclude, metrics.path)
+		}
+		if err := newOpenAPI(*c.openAPI, c.auth, exclude...).register(app); err != nil {
+			return nil, err
+		}
+	}
+	if proxy != nil {
+		app.Use(proxy.middleware)
+	}
+	active := &inFlight{}
+	app.Use(active.middleware)
+	app.Use(tracingMiddleware)
+	app.Use(requestIDMiddleware)
+	if c.clientCAs != nil || (c.tlsFiles != nil && c.tlsFiles.ClientCAFile != "") {
+		app.Use(peerIdentityMiddleware)
+	}
+	if metrics != nil {
+		app.Use(metrics.middleware)
+	}
+	// Shed requests are counted by metrics but kept out of the access log,
+	// which would otherwise flood at error level during an overload.
+	if c.concurrencyLimit != nil {
+		var registry *prometheus.Registry
+		if metrics != nil {
+			registry = metrics.registry
+		}
+		limiter, err := newConcurrencyLimiter(*c.concurrencyLimit, registry)
+		if err != nil {
+			return nil, err
+		}
+		app.Use(limiter.middleware)
+	}
+	if c.accessLog != nil {
+		app.Use(accessLogMiddleware(*c.accessLog))
+	}
+	app.Use(recoverMiddleware)
+	if c.propagateDeadline {
+		app.Use(deadlineMiddleware)
+	}
+	if c.timeout != nil {
+		app.Use(timeoutMiddleware(*c.timeout))
+	}
+	if !c.disableCORS {
+		corsConfig := defaultCORSConfig()
+		if c.cors != nil {
+			corsConfig = *c.cors
+		}
+		corsHandler, err := corsMiddleware(corsConfig)
+		if err != nil {
+			return nil, err
+		}
+		app.Use(corsHandler)
+	}
+	var jwks *jwks
+	if c.auth != nil {
+		auth, err := newAuthenticator(*c.auth)
+		if err != nil {
+			return nil, err
+		}
+		jwks = auth.jwks
+		app.Use(auth.middleware)
+	}
+	if c.rateLimit != nil {
+		rateLimit, err := rateLimitMiddleware(*c.rateLimit)
+		if err != nil {
+			return nil, err
+		}
+		app.Use(rateLimit)
+	}
+	if c.idempotency != nil {
+		app.Use(Idempotency(*c.idempotency))
+	}
+	app.Use((&routes{}).middleware)
 	listenConfig := fiber.ListenConfig{
 		DisableStartupMessage: true,
 	}
-	if len(c.certificates) > 0 {
-		listenConfig.AutoCertManager = &autocert.Manager{}
-		tlsHandler := &fiber.TLSHandler{}
-		listenConfig.TLSConfigFunc = func(tlsConfig *tls.Config) {
-			tlsConfig.Certificates = c.certificates
-			tlsConfig.GetCertificate = tlsHandler.GetClientInfo
-			tlsConfig.NextProtos = nil
-			if c.clientCAs != nil {
-				tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
-				tlsConfig.ClientCAs = c.clientCAs
-			}
+	var certReloader *certReloader
+	if c.tlsFiles != nil {
+		if len(c.certificates) > 0 || c.clientCAs != nil {
+			return nil, errors.New("server: WithTLSFiles cannot be combined with WithCertificate or WithClientAuth")
+		}
+		var err error
+		if certReloader, err = newCertReloader(*c.tlsFiles); err != nil {
+			return nil, err
+		}
+	}
+	var tlsConfig *tls.Config
+	var acmeChallenge *acmeChallengeServer
+	tlsHandler := &fiber.TLSHandler{}
+	switch {
+	case c.acme != nil:
+		if len(c.certificates) > 0 || c.clientCAs != nil || c.tlsFiles != nil {
+			return nil, errors.New("server: WithACME cannot be combined with other certificate options")
+		}
+		manager, err := newACMEManager(*c.acme)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig = &tls.Config{
+			MinVersion: tls.VersionTLS12,
+			GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+				_, _ = tlsHandler.GetClientInfo(hello)
+				return manager.GetCertificate(hello)
+			},
+			NextProtos: []string{"http/1.1", acme.ALPNProto},
+		}
+		if c.acme.HTTPChallengePort != "" {
+			acmeChallenge = newACMEChallengeServer(c.acme.HTTPChallengePort, manager)
+		}
+	case len(c.certificates) > 0 || certReloader != nil:
+		tlsConfig = &tls.Config{
+			MinVersion:     tls.VersionTLS12,
+			Certificates:   c.certificates,
+			GetCertificate: tlsHandler.GetClientInfo,
 		}
+		if c.clientCAs != nil {
+			tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+			tlsConfig.ClientCAs = c.clientCAs
+		}
+		if certReloader != nil {
+			certReloader.apply(tlsConfig)
+		}
+	}
+	if tlsConfig != nil {
 		app.SetTLSHandler(tlsHandler)
 	}
 	return &server{
-		app:          app,
-		listenConfig: listenConfig,
+		app:           app,
+		listenConfig:  listenConfig,
+		health:        c.health,
+		admin:         admin,
+		adminConfig:   c.admin,
+		tlsConfig:     tlsConfig,
+		certReloader:  certReloader,
+		jwks:          jwks,
+		acmeChallenge: acmeChallenge,
+		shutdown:      c.shutdown,
+		shutdownHooks: c.shutdownHooks,
+		inFlight:      active,
 	}, nil
 }
 
@@ -79,16 +248,99 @@ func (s *server) Run(ctx context.Context, port string) error {
 		slog.InfoContext(ctx, "Shutting down server")
 		return nil
 	})
+	// Hooks and the telemetry flush run last, once everything else has shut
+	// down, but only if the listeners came up: a Run that fails to bind has
+	// started nothing for them to clean up.
+	var listening bool
+	defer func() {
+		if listening {
+			s.runShutdownHooks(ctx)
+		}
+	}()
 
 	port = ":" + strings.TrimPrefix(port, ":")
-	gracefulContext, cancel := signal.NotifyContext(ctx, os.Interrupt, os.Kill)
+	signalContext, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	stopContext, cancel := context.WithCancel(signalContext)
 	defer cancel()
-	s.listenConfig.GracefulContext = gracefulContext
-	s.listenConfig.OnShutdownError = func(err error) {
-		slog.ErrorContext(ctx, "Shutdown server error", slog.Any("error", err))
+	if s.certReloader != nil {
+		go s.certReloader.watch(stopContext)
+	}
+	if s.jwks != nil {
+		go s.jwks.watch(stopContext)
+	}
+	if s.acmeChallenge != nil {
+		if err := s.acmeChallenge.start(ctx); err != nil {
+			return fmt.Errorf("server: listen acme challenge: %w", err)
+		}
+		defer s.acmeChallenge.shutdown(ctx)
+	}
+
+	adminErr := make(chan error, 1)
+	if s.admin != nil {
+		// Bind the admin port up front so a conflict fails Run before the public
+		// listener starts accepting traffic.
+		adminListener, err := net.Listen(fiber.NetworkTCP4, s.adminConfig.address())
+		if err != nil {
+			return fmt.Errorf("server: listen admin: %w", err)
+		}
+		s.admin.Hooks().OnListen(func(listenData fiber.ListenData) error {
+			slog.InfoContext(ctx, "Running admin server", slog.String("url", fmt.Sprintf("http://%s:%s", listenData.Host, listenData.Port)))
+			return nil
+		})
+		go func() {
+			err := s.admin.Listener(adminListener, fiber.ListenConfig{
+				DisableStartupMessage: true,
+			})
+			if err != nil {
+				cancel()
+			}
+			adminErr <- err
+		}()
+		// The admin server stops last so probes and metrics stay available while draining.
+		defer func() {
+			if err := s.admin.ShutdownWithTimeout(adminShutdownTimeout); err != nil {
+				slog.ErrorContext(ctx, "Shutdown admin server error", slog.Any("error", err))
+			}
+			adminListener.Close()
+		}()
+	}
+
+	ln, err := s.bind(port)
+	if err != nil {
+		return err
+	}
+	listening = true
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- s.app.Listener(ln, s.listenConfig)
+	}()
+
+	select {
+	case err = <-serveErr:
+	case <-stopContext.Done():
+		// Later signals are handled by drain, or end the process.
+		stop()
+		s.drain(ctx)
+		err = <-serveErr
+	}
+	select {
+	case adminErr := <-adminErr:
+		if !errors.Is(adminErr, net.ErrClosed) {
+			err = errors.Join(err, adminErr)
+		}
+	default:
+	}
+	return err
+}
+
+func (s *server) bind(port string) (net.Listener, error) {
+	ln, err := net.Listen(fiber.NetworkTCP4, port)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen: %w", err)
 	}
-	s.listenConfig.OnShutdownSuccess = func() {
-		slog.InfoContext(ctx, "Shutdown server successfully")
+	if s.tlsConfig != nil {
+		ln = tls.NewListener(ln, s.tlsConfig)
 	}
-	return s.app.Listen(port, s.listenConfig)
+	return ln, nil
 }
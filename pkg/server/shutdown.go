@@ -0,0 +1,99 @@
+package server
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"sync/atomic"
+	"syscall"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"github.com/nphiro/mesh/pkg/cmlt"
+)
+
+type ShutdownConfig struct {
+	// PreStopDelay keeps serving after readiness starts failing, so load
+	// balancers can deregister the instance before its listener closes. A
+	// second signal or the end of Run's context ends the delay early; a
+	// signal after that exits the process.
+	PreStopDelay time.Duration
+	// DrainTimeout bounds the wait for in-flight requests; requests still
+	// running afterwards are cut off. Defaults to 10 seconds.
+	DrainTimeout time.Duration
+	// HookTimeout bounds all shutdown hooks together. Defaults to 10 seconds.
+	HookTimeout time.Duration
+}
+
+type shutdownHook struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+// inFlight counts requests inside the handler chain, to report what a
+// drain timeout cut off.
+type inFlight struct {
+	atomic.Int64
+}
+
+func (n *inFlight) middleware(c fiber.Ctx) error {
+	n.Add(1)
+	defer n.Add(-1)
+	return c.Next()
+}
+
+// drain fails readiness, waits out the pre-stop delay and stops the public
+// listener, waiting up to the drain timeout for in-flight requests.
+func (s *server) drain(ctx context.Context) {
+	s.health.draining.Store(true)
+	slog.InfoContext(ctx, "Draining server",
+		slog.Int64("pre_stop_delay_ms", s.shutdown.PreStopDelay.Milliseconds()),
+		slog.Int64("drain_timeout_ms", s.shutdown.DrainTimeout.Milliseconds()),
+	)
+	s.preStop(ctx)
+	err := s.app.ShutdownWithTimeout(s.shutdown.DrainTimeout)
+	switch n := s.inFlight.Load(); {
+	case n > 0:
+		slog.WarnContext(ctx, "Cut off in-flight requests", slog.Int64("count", n))
+	case err != nil:
+		slog.ErrorContext(ctx, "Shutdown server error", slog.Any("error", err))
+	default:
+		slog.InfoContext(ctx, "Shutdown server successfully")
+	}
+}
+
+// preStop waits out the pre-stop delay unless another signal arrives first.
+// Signals are left to their default handling afterwards, so a further one
+// terminates the process.
+func (s *server) preStop(ctx context.Context) {
+	if s.shutdown.PreStopDelay <= 0 {
+		return
+	}
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(signals)
+	timer := time.NewTimer(s.shutdown.PreStopDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	case sig := <-signals:
+		slog.WarnContext(ctx, "Cut pre-stop delay short", slog.String("signal", sig.String()))
+	}
+}
+
+// runShutdownHooks runs the hooks in registration order and flushes
+// telemetry last, so the hooks' own logs and spans are delivered.
+func (s *server) runShutdownHooks(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.shutdown.HookTimeout)
+	defer cancel()
+	for _, hook := range s.shutdownHooks {
+		if err := hook.fn(ctx); err != nil {
+			slog.ErrorContext(ctx, "Shutdown hook failed", slog.String("hook", hook.name), slog.Any("error", err))
+		}
+	}
+	if err := cmlt.Flush(); err != nil {
+		slog.ErrorContext(ctx, "Flush telemetry error", slog.Any("error", err))
+	}
+}
@@ -0,0 +1,82 @@
+package server
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func freePort(t *testing.T) (net.Listener, string) {
+	t.Helper()
+	ln, err := net.Listen("tcp4", ":0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ln, strconv.Itoa(ln.Addr().(*net.TCPAddr).Port)
+}
+
+func newHookedServer(t *testing.T, opts ...Option) (Server, *atomic.Int32) {
+	t.Helper()
+	var hooks atomic.Int32
+	opts = append(opts, WithShutdownHook("count", func(context.Context) error {
+		hooks.Add(1)
+		return nil
+	}))
+	s, err := New(opts...)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s, &hooks
+}
+
+func TestRunSkipsShutdownHooksWhenBindFails(t *testing.T) {
+	taken, port := freePort(t)
+	defer taken.Close()
+	s, hooks := newHookedServer(t)
+
+	if err := s.Run(context.Background(), port); err == nil {
+		t.Fatal("Run succeeded on a port in use")
+	}
+	if n := hooks.Load(); n != 0 {
+		t.Errorf("shutdown hooks ran %d times, want 0", n)
+	}
+}
+
+func TestRunCancelEndsPreStopDelay(t *testing.T) {
+	ln, port := freePort(t)
+	ln.Close()
+	s, hooks := newHookedServer(t, WithGracefulShutdown(ShutdownConfig{PreStopDelay: time.Hour}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- s.Run(ctx, port)
+	}()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		conn, err := net.Dial("tcp4", "127.0.0.1:"+port)
+		if err == nil {
+			conn.Close()
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("server never listened: %v", err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("Run = %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Run still waiting out the pre-stop delay after its context ended")
+	}
+	if n := hooks.Load(); n != 1 {
+		t.Errorf("shutdown hooks ran %d times, want 1", n)
+	}
+}
@@ -0,0 +1,8 @@
+window.onload = function () {
+  var root = document.getElementById("swagger-ui");
+  window.ui = SwaggerUIBundle({
+    url: root.dataset.url,
+    dom_id: "#swagger-ui",
+    deepLinking: true,
+  });
+};
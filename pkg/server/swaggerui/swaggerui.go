@@ -0,0 +1,11 @@
+// Package swaggerui embeds Swagger UI for server.OpenAPIConfig. It is kept
+// out of pkg/server so services without a docs page don't link its 1.5MB of
+// assets.
+package swaggerui
+
+import "embed"
+
+// FS holds swagger-ui-bundle.js, swagger-ui.css and init.js at its root.
+//
+//go:embed swagger-ui-bundle.js swagger-ui.css init.js
+var FS embed.FS
@@ -0,0 +1,169 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TimeoutConfig bounds requests with a context deadline. Handlers are not
+// preempted: one that ignores its context keeps the connection until it returns,
+// and a response it completes after the deadline is still sent.
+type TimeoutConfig struct {
+	// Default is the budget of every request. Routes override it with Timeout.
+	Default time.Duration
+}
+
+var timeoutKey localsKey = "timeout"
+
+// requestTimeout is the request context of a timed request. Its deadline can be
+// moved by route middleware after other middleware has derived contexts from it,
+// so values they added stay in place. The budget counts from the start of the
+// request and never outlives the parent's own deadline.
+type requestTimeout struct {
+	parent context.Context
+	start  time.Time
+	done   chan struct{}
+
+	mu         sync.Mutex
+	budget     time.Duration
+	timer      *time.Timer
+	err        error
+	stopParent func() bool
+}
+
+func newRequestTimeout(parent context.Context, budget time.Duration) *requestTimeout {
+	t := &requestTimeout{
+		parent: parent,
+		start:  time.Now(),
+		done:   make(chan struct{}),
+	}
+	t.stopParent = context.AfterFunc(parent, func() {
+		t.finish(parent.Err())
+	})
+	t.setBudget(budget)
+	return t
+}
+
+// setBudget replaces the budget; zero leaves only the parent's deadline.
+func (t *requestTimeout) setBudget(budget time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.budget = budget
+	if t.timer != nil {
+		t.timer.Stop()
+		t.timer = nil
+	}
+	if budget > 0 {
+		t.timer = time.AfterFunc(time.Until(t.start.Add(budget)), func() {
+			t.finish(context.DeadlineExceeded)
+		})
+	}
+}
+
+func (t *requestTimeout) finish(err error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.err != nil {
+		return
+	}
+	t.err = err
+	if t.timer != nil {
+		t.timer.Stop()
+	}
+	close(t.done)
+}
+
+func (t *requestTimeout) release() {
+	t.stopParent()
+	t.finish(context.Canceled)
+}
+
+func (t *requestTimeout) Deadline() (time.Time, bool) {
+	deadline, ok := t.parent.Deadline()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if own := t.start.Add(t.budget); t.budget > 0 && (!ok || own.Before(deadline)) {
+		return own, true
+	}
+	return deadline, ok
+}
+
+func (t *requestTimeout) Done() <-chan struct{} {
+	return t.done
+}
+
+func (t *requestTimeout) Err() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.err
+}
+
+func (t *requestTimeout) Value(key any) any {
+	return t.parent.Value(key)
+}
+
+// Timeout is route middleware giving the route its own budget in place of the
+// WithTimeout default. A zero budget lifts the default, e.g. for streaming.
+// Like WithTimeout, it cannot stop a handler that ignores its context.
+func Timeout(budget time.Duration) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		if t, ok := c.Locals(timeoutKey).(*requestTimeout); ok {
+			t.setBudget(budget)
+			return c.Next()
+		}
+		return runWithTimeout(c, budget)
+	}
+}
+
+func timeoutMiddleware(cfg TimeoutConfig) fiber.Handler {
+	return func(c fiber.Ctx) error {
+		return runWithTimeout(c, cfg.Default)
+	}
+}
+
+func runWithTimeout(c fiber.Ctx, budget time.Duration) error {
+	parent := c.Context()
+	t := newRequestTimeout(parent, budget)
+	defer t.release()
+	c.SetContext(t)
+	c.Locals(timeoutKey, t)
+
+	err := next(c)
+	if !errors.Is(t.Err(), context.DeadlineExceeded) {
+		return err
+	}
+	// The deadline belongs to the caller when it expired on the parent context,
+	// e.g. one set from TimeoutHeader by WithDeadlinePropagation.
+	exceededUpstream := parent.Err() != nil
+	source := "server"
+	if exceededUpstream {
+		source = "upstream"
+	}
+	t.mu.Lock()
+	budget = t.budget
+	t.mu.Unlock()
+	trace.SpanFromContext(t).SetAttributes(
+		attribute.Bool("http.timeout", true),
+		attribute.String("http.timeout.source", source),
+		attribute.Int64("http.timeout.budget_ms", budget.Milliseconds()),
+	)
+	// Handlers that finished in time for a response keep it; only failures
+	// caused by the deadline are replaced.
+	if c.Response().StatusCode() < fiber.StatusInternalServerError {
+		return err
+	}
+	c.Response().ResetBody()
+	if exceededUpstream {
+		return NewProblem(fiber.StatusGatewayTimeout, "caller deadline exceeded")
+	}
+	return NewProblem(fiber.StatusServiceUnavailable, "request exceeded its "+budget.String()+" budget")
+}
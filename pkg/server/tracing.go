@@ -0,0 +1,74 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gofiber/fiber/v3"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "mesh.pkg/server"
+
+type headerCarrier struct {
+	c fiber.Ctx
+}
+
+func (h headerCarrier) Get(key string) string {
+	return h.c.Get(key)
+}
+
+func (h headerCarrier) Set(key, value string) {
+	h.c.Request().Header.Set(key, value)
+}
+
+func (h headerCarrier) Keys() []string {
+	headers := h.c.GetReqHeaders()
+	keys := make([]string, 0, len(headers))
+	for key := range headers {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+func tracingMiddleware(c fiber.Ctx) error {
+	// Values from fiber.Ctx are only valid during the request, while span attributes outlive it.
+	method := strings.Clone(c.Method())
+	ctx := otel.GetTextMapPropagator().Extract(c.Context(), headerCarrier{c})
+	ctx, span := otel.Tracer(tracerName).Start(ctx, method,
+		trace.WithSpanKind(trace.SpanKindServer),
+		trace.WithAttributes(
+			attribute.String("http.request.method", method),
+			attribute.String("url.path", strings.Clone(c.Path())),
+			attribute.String("url.scheme", strings.Clone(c.Scheme())),
+			attribute.String("server.address", strings.Clone(c.Hostname())),
+			attribute.String("client.address", ClientIP(c)),
+			attribute.String("user_agent.original", strings.Clone(c.Get(fiber.HeaderUserAgent))),
+			attribute.Int("http.request.body.size", len(c.Request().Body())),
+		),
+	)
+	defer span.End()
+	c.SetContext(ctx)
+
+	err := next(c)
+
+	status := c.Response().StatusCode()
+	if route, ok := routeTemplate(c); ok {
+		span.SetName(method + " " + route)
+		span.SetAttributes(attribute.String("http.route", route))
+	}
+	span.SetAttributes(
+		attribute.Int("http.response.status_code", status),
+		attribute.Int("http.response.body.size", len(c.Response().Body())),
+	)
+	if handlerErr := handlerError(c); handlerErr != nil {
+		span.RecordError(handlerErr)
+	}
+	if status >= fiber.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	return err
+}
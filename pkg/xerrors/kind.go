@@ -0,0 +1,32 @@
+package xerrors
+
+import "errors"
+
+type Kind string
+
+const (
+	KindUnknown          Kind = ""
+	KindInvalidArgument  Kind = "invalid_argument"
+	KindUnauthenticated  Kind = "unauthenticated"
+	KindPermissionDenied Kind = "permission_denied"
+	KindNotFound         Kind = "not_found"
+	KindConflict         Kind = "conflict"
+	KindPrecondition     Kind = "failed_precondition"
+	KindRateLimited      Kind = "rate_limited"
+	KindUnavailable      Kind = "unavailable"
+	KindTimeout          Kind = "timeout"
+	KindInternal         Kind = "internal"
+)
+
+type kinded interface {
+	Kind() Kind
+}
+
+// KindOf returns the kind of the outermost error in the chain that carries one.
+func KindOf(err error) Kind {
+	var k kinded
+	if errors.As(err, &k) {
+		return k.Kind()
+	}
+	return KindUnknown
+}
@@ -1,16 +1,26 @@
 package xerrors
 
 import (
+	"errors"
 	"runtime"
 )
 
 type custom struct {
 	err    error
 	frames []uintptr
+	kind   Kind
 }
 
 func Wrap(err error) error {
-	return &custom{err, callers(0)}
+	return &custom{err, callers(0), KindOf(err)}
+}
+
+func New(kind Kind, message string) error {
+	return &custom{errors.New(message), callers(0), kind}
+}
+
+func WithKind(kind Kind, err error) error {
+	return &custom{err, callers(0), kind}
 }
 
 func callers(skip int) []uintptr {
@@ -24,6 +34,14 @@ func (e *custom) Error() string {
 	return e.err.Error()
 }
 
+func (e *custom) Unwrap() error {
+	return e.err
+}
+
 func (e *custom) StackFrames() []uintptr {
 	return e.frames
 }
+
+func (e *custom) Kind() Kind {
+	return e.kind
+}